@@ -6,9 +6,12 @@ See AUTHORS and LICENSE for the license details and contributors.
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	devkitcmd "github.com/macaroni-os/anise-repo-devkit/pkg/cmd"
 	"github.com/macaroni-os/anise-repo-devkit/pkg/devkit"
@@ -67,16 +70,39 @@ func Execute() {
 		},
 	}
 
-	rootCmd.PersistentFlags().StringArrayP("tree", "t", []string{}, "Path of the tree to use.")
+	rootCmd.PersistentFlags().StringArrayP("tree", "t", []string{},
+		"Path, git URL ([git+]url[#ref]) or tarball (tree.tar[.gz|.zst]) of the tree to use.")
+	rootCmd.PersistentFlags().StringArray("build-tree", []string{},
+		"Path, git URL or tarball of the tree with the compiler definitions. Default are the --tree paths.")
+	rootCmd.PersistentFlags().String("trees-cache-dir", "",
+		"Directory where the git and tarball trees are checked out.")
 	rootCmd.PersistentFlags().StringP("specs-file", "s", "", "Path of the devkit specification file.")
+	rootCmd.PersistentFlags().StringP("repo", "r", "",
+		"Name of the repository defined in the specs file to use.")
 	rootCmd.PersistentFlags().BoolP("debug", "d", false, "Enable debug logging.")
+	rootCmd.PersistentFlags().String("journal-file", "",
+		"Path of the journal of the write operations.")
 
 	rootCmd.AddCommand(
 		devkitcmd.NewCleanCommand(),
 		devkitcmd.NewPkgsCommand(),
+		devkitcmd.NewPurgeNoncurrentCommand(),
+		devkitcmd.NewJournalCommand(),
+		devkitcmd.NewInfoCommand(),
+		devkitcmd.NewOwnerCommand(),
+		devkitcmd.NewCollisionsCommand(),
+		devkitcmd.NewCheckInstallableCommand(),
+		devkitcmd.NewStatsCommand(),
+		devkitcmd.NewLicensesCommand(),
+		devkitcmd.NewSBOMCommand(),
 	)
 
-	if err := rootCmd.Execute(); err != nil {
+	// Cancel the in-flight operations on SIGINT/SIGTERM.
+	ctx, stop := signal.NotifyContext(context.Background(),
+		os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
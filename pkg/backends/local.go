@@ -5,8 +5,10 @@ See AUTHORS and LICENSE for the license details and contributors.
 package backends
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -46,9 +48,28 @@ func NewBackendLocal(specs *specs.AniseRDConfig, path string) (*BackendLocal, er
 	return ans, nil
 }
 
-func (b *BackendLocal) GetFilesList() ([]string, error) {
+func (b *BackendLocal) GetFilesList(ctx context.Context) ([]string, error) {
 	ans := []string{}
 
+	files, err := b.GetFilesInfo(ctx)
+	if err != nil {
+		return ans, err
+	}
+
+	for _, f := range files {
+		ans = append(ans, f.Name)
+	}
+
+	return ans, nil
+}
+
+func (b *BackendLocal) GetFilesInfo(ctx context.Context) ([]specs.RepoFile, error) {
+	ans := []specs.RepoFile{}
+
+	if ctx.Err() != nil {
+		return ans, ctx.Err()
+	}
+
 	files, err := ioutil.ReadDir(b.Path)
 	if err != nil {
 		return ans, err
@@ -62,13 +83,21 @@ func (b *BackendLocal) GetFilesList() ([]string, error) {
 			continue
 		}
 
-		ans = append(ans, f.Name())
+		ans = append(ans, specs.RepoFile{
+			Name:         f.Name(),
+			Size:         f.Size(),
+			LastModified: f.ModTime(),
+		})
 	}
 
 	return ans, nil
 }
 
-func (b *BackendLocal) GetMetadata(file string) (*artifact.PackageArtifact, error) {
+func (b *BackendLocal) GetMetadata(ctx context.Context, file string) (*artifact.PackageArtifact, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
 	metafile := filepath.Join(b.Path, file)
 	content, err := ioutil.ReadFile(metafile)
 	if err != nil {
@@ -79,7 +108,32 @@ func (b *BackendLocal) GetMetadata(file string) (*artifact.PackageArtifact, erro
 	return artifact.NewPackageArtifactFromYaml(content)
 }
 
-func (b *BackendLocal) CleanFile(file string) error {
+func (b *BackendLocal) DownloadFile(ctx context.Context, file, dst string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	src, err := os.Open(filepath.Join(b.Path, file))
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, src)
+	return err
+}
+
+func (b *BackendLocal) CleanFile(ctx context.Context, file string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
 	absFile := filepath.Join(b.Path, file)
 	return os.Remove(absFile)
 }
@@ -0,0 +1,421 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package backends
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/macaroni-os/anise-repo-devkit/pkg/specs"
+
+	artifact "github.com/geaaru/luet/pkg/v2/compiler/types/artifact"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// BackendMemoryCall describes a call received by the memory backend.
+type BackendMemoryCall struct {
+	Method string
+	File   string
+}
+
+// BackendMemory is an in-memory backend that records every call.
+// It's used to test the devkit without a real repository.
+type BackendMemory struct {
+	Specs *specs.AniseRDConfig
+	Files map[string][]byte
+	Calls []BackendMemoryCall
+
+	// Errors to return on access to a specific file.
+	Errors map[string]error
+	// Optional function called before the removal of a file.
+	OnClean func(file string)
+
+	// The backend keeps the versions of the files like a versioned bucket.
+	Versioned bool
+	// Remove all versions of the files on cleanup.
+	RemoveAllVersions bool
+
+	modTimes map[string]time.Time
+	// The versions of every file, from the oldest to the newest.
+	versions    map[string][]memoryVersion
+	lastVersion int
+	mutex       sync.Mutex
+}
+
+type memoryVersion struct {
+	specs.ObjectVersion
+	Content []byte
+}
+
+func NewBackendMemory(specs *specs.AniseRDConfig) *BackendMemory {
+	return &BackendMemory{
+		Specs:  specs,
+		Files:  make(map[string][]byte, 0),
+		Calls:  []BackendMemoryCall{},
+		Errors: make(map[string]error, 0),
+
+		modTimes: make(map[string]time.Time, 0),
+		versions: make(map[string][]memoryVersion, 0),
+	}
+}
+
+func (b *BackendMemory) AddFile(file string, content []byte) {
+	b.AddVersion(file, content, time.Now())
+}
+
+// AddVersion adds a new version of the file created at the specified
+// time. Without versioning the previous versions are dropped.
+func (b *BackendMemory) AddVersion(file string, content []byte, modTime time.Time) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.Files[file] = content
+	b.modTimes[file] = modTime
+	b.addVersion(file, content, modTime, false)
+}
+
+// AddDeleteMarker removes the file of a versioned backend with a delete
+// marker created at the specified time.
+func (b *BackendMemory) AddDeleteMarker(file string, modTime time.Time) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	delete(b.Files, file)
+	b.addVersion(file, nil, modTime, true)
+}
+
+func (b *BackendMemory) addVersion(file string, content []byte, modTime time.Time, marker bool) {
+	b.lastVersion++
+	v := memoryVersion{
+		ObjectVersion: specs.ObjectVersion{
+			File:           file,
+			VersionID:      fmt.Sprintf("v%d", b.lastVersion),
+			Size:           int64(len(content)),
+			LastModified:   modTime,
+			IsDeleteMarker: marker,
+		},
+		Content: content,
+	}
+
+	if b.Versioned {
+		b.versions[file] = append(b.versions[file], v)
+	} else {
+		b.versions[file] = []memoryVersion{v}
+	}
+}
+
+// GetVersions returns the versions of the file from the oldest
+// to the newest.
+func (b *BackendMemory) GetVersions(file string) []specs.ObjectVersion {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	ans := []specs.ObjectVersion{}
+	for _, v := range b.versions[file] {
+		ans = append(ans, v.ObjectVersion)
+	}
+	return ans
+}
+
+func (b *BackendMemory) HasFile(file string) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	_, ok := b.Files[file]
+	return ok
+}
+
+// AddArtifact adds the metadata file of the artifact and, if withTarball
+// is true, an empty tarball with the name defined in the artifact path.
+func (b *BackendMemory) AddArtifact(metaFile string, art *artifact.PackageArtifact, withTarball bool) error {
+	data, err := yaml.Marshal(art)
+	if err != nil {
+		return err
+	}
+
+	b.AddFile(metaFile, data)
+	if withTarball {
+		b.AddFile(filepath.Base(art.Path), []byte{})
+	}
+
+	return nil
+}
+
+// LoadFixtures seeds the backend with the files of the directory.
+func (b *BackendMemory) LoadFixtures(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return err
+		}
+		b.AddFile(e.Name(), data)
+	}
+
+	return nil
+}
+
+// GetCalls returns the calls received for the specified method.
+func (b *BackendMemory) GetCalls(method string) []BackendMemoryCall {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	ans := []BackendMemoryCall{}
+	for _, c := range b.Calls {
+		if c.Method == method {
+			ans = append(ans, c)
+		}
+	}
+	return ans
+}
+
+func (b *BackendMemory) record(method, file string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.Calls = append(b.Calls, BackendMemoryCall{Method: method, File: file})
+	if err, ok := b.Errors[file]; ok {
+		return err
+	}
+	return nil
+}
+
+func (b *BackendMemory) GetFilesList(ctx context.Context) ([]string, error) {
+	ans := []string{}
+
+	if ctx.Err() != nil {
+		return ans, ctx.Err()
+	}
+
+	if err := b.record("GetFilesList", ""); err != nil {
+		return ans, err
+	}
+
+	b.mutex.Lock()
+	for f := range b.Files {
+		ans = append(ans, f)
+	}
+	b.mutex.Unlock()
+
+	sort.Strings(ans)
+
+	return ans, nil
+}
+
+func (b *BackendMemory) GetFilesInfo(ctx context.Context) ([]specs.RepoFile, error) {
+	ans := []specs.RepoFile{}
+
+	files, err := b.GetFilesList(ctx)
+	if err != nil {
+		return ans, err
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for _, f := range files {
+		ans = append(ans, specs.RepoFile{
+			Name:         f,
+			Size:         int64(len(b.Files[f])),
+			LastModified: b.modTimes[f],
+		})
+	}
+
+	return ans, nil
+}
+
+func (b *BackendMemory) GetMetadata(ctx context.Context, file string) (*artifact.PackageArtifact, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if err := b.record("GetMetadata", file); err != nil {
+		return nil, err
+	}
+
+	b.mutex.Lock()
+	data, ok := b.Files[file]
+	b.mutex.Unlock()
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("Error on open file %s", file))
+	}
+
+	return artifact.NewPackageArtifactFromYaml(data)
+}
+
+func (b *BackendMemory) DownloadFile(ctx context.Context, file, dst string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if err := b.record("DownloadFile", file); err != nil {
+		return err
+	}
+
+	b.mutex.Lock()
+	data, ok := b.Files[file]
+	b.mutex.Unlock()
+	if !ok {
+		return errors.New(fmt.Sprintf("Error on open file %s", file))
+	}
+
+	return os.WriteFile(dst, data, 0644)
+}
+
+func (b *BackendMemory) CleanFile(ctx context.Context, file string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if b.OnClean != nil {
+		b.OnClean(file)
+	}
+
+	if err := b.record("CleanFile", file); err != nil {
+		return err
+	}
+
+	_, err := b.cleanFile(file)
+	return err
+}
+
+// cleanFile removes the file and returns the versions removed.
+func (b *BackendMemory) cleanFile(file string) ([]memoryVersion, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.Versioned && b.RemoveAllVersions {
+		ans := b.versions[file]
+		if len(ans) == 0 {
+			return nil, errors.New(fmt.Sprintf("Object %s not found", file))
+		}
+		delete(b.versions, file)
+		delete(b.Files, file)
+		return ans, nil
+	}
+
+	if _, ok := b.Files[file]; !ok {
+		return nil, errors.New(fmt.Sprintf("File %s not found", file))
+	}
+	delete(b.Files, file)
+
+	if b.Versioned {
+		// Only a delete marker is added.
+		b.addVersion(file, nil, time.Now(), true)
+		return []memoryVersion{{ObjectVersion: specs.ObjectVersion{File: file}}}, nil
+	}
+
+	ans := b.versions[file]
+	delete(b.versions, file)
+	return ans, nil
+}
+
+// CleanFiles removes the files with a single call. The results are
+// returned in the same order of the files.
+func (b *BackendMemory) CleanFiles(ctx context.Context, files []string) []specs.CleanResult {
+	ans := []specs.CleanResult{}
+
+	b.record("CleanFiles", "")
+	for _, f := range files {
+		r := specs.CleanResult{File: f}
+
+		if b.OnClean != nil {
+			b.OnClean(f)
+		}
+
+		if ctx.Err() != nil {
+			r.Error = ctx.Err()
+		} else if r.Error = b.record("CleanFile", f); r.Error == nil {
+			var versions []memoryVersion
+			versions, r.Error = b.cleanFile(f)
+			for _, v := range versions {
+				r.Versions++
+				r.Reclaimed += v.Size
+			}
+		}
+		ans = append(ans, r)
+	}
+
+	return ans
+}
+
+func (b *BackendMemory) IsVersioned() bool { return b.Versioned }
+
+func (b *BackendMemory) GetNoncurrentVersions(ctx context.Context, before time.Time) ([]specs.ObjectVersion, error) {
+	ans := []specs.ObjectVersion{}
+
+	if ctx.Err() != nil {
+		return ans, ctx.Err()
+	}
+
+	if err := b.record("GetNoncurrentVersions", ""); err != nil {
+		return ans, err
+	}
+
+	b.mutex.Lock()
+	for _, list := range b.versions {
+		for _, v := range list {
+			ans = append(ans, v.ObjectVersion)
+		}
+	}
+	b.mutex.Unlock()
+
+	return selectNoncurrentVersions(ans, before), nil
+}
+
+func (b *BackendMemory) PurgeVersions(ctx context.Context, versions []specs.ObjectVersion) []specs.CleanResult {
+	ans := []specs.CleanResult{}
+
+	for _, v := range versions {
+		r := specs.CleanResult{File: v.File}
+		if r.Error = b.record("PurgeVersions", v.File); r.Error == nil {
+			r.Error = b.purgeVersion(v)
+		}
+		if r.Error == nil {
+			r.Versions = 1
+			r.Reclaimed = v.Size
+		}
+		ans = append(ans, r)
+	}
+
+	return ans
+}
+
+func (b *BackendMemory) purgeVersion(v specs.ObjectVersion) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	list := b.versions[v.File]
+	for idx := range list {
+		if list[idx].VersionID != v.VersionID {
+			continue
+		}
+
+		list = append(list[:idx], list[idx+1:]...)
+		b.versions[v.File] = list
+
+		// The current file is the newest version left.
+		delete(b.Files, v.File)
+		if len(list) == 0 {
+			delete(b.versions, v.File)
+		} else if last := list[len(list)-1]; !last.IsDeleteMarker {
+			b.Files[v.File] = last.Content
+			b.modTimes[v.File] = last.LastModified
+		}
+		return nil
+	}
+
+	return errors.New(fmt.Sprintf("Version %s of %s not found", v.VersionID, v.File))
+}
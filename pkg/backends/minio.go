@@ -16,7 +16,6 @@ import (
 
 	artifact "github.com/geaaru/luet/pkg/v2/compiler/types/artifact"
 	"github.com/minio/minio-go/v7"
-	"github.com/minio/minio-go/v7/pkg/credentials"
 )
 
 type BackendMinio struct {
@@ -25,6 +24,12 @@ type BackendMinio struct {
 
 	MinioClient *minio.Client
 	Bucket      string
+	Retry       *RetryPolicy
+
+	// The bucket has the versioning enabled or suspended.
+	Versioned bool
+	// Remove all versions of the objects on cleanup of a versioned bucket.
+	RemoveAllVersions bool
 }
 
 func NewBackendMinio(specs *specs.AniseRDConfig, path string, opts map[string]string) (*BackendMinio, error) {
@@ -52,18 +57,16 @@ func NewBackendMinio(specs *specs.AniseRDConfig, path string, opts map[string]st
 		return nil, errors.New("Minio endpoint is mandatory")
 	}
 
-	if _, ok := opts["minio-keyid"]; !ok {
-		return nil, errors.New("Minio key ID is mandatory")
-	}
-
-	if _, ok := opts["minio-secret"]; !ok {
-		return nil, errors.New("Minio secret Access key is mandatory")
+	retry, err := NewRetryPolicy(opts)
+	if err != nil {
+		return nil, err
 	}
 
 	ans := &BackendMinio{
 		Specs:        specs,
 		ArtefactPath: path,
 		Bucket:       opts["minio-bucket"],
+		Retry:        retry,
 	}
 
 	minioRegion := ""
@@ -79,14 +82,14 @@ func NewBackendMinio(specs *specs.AniseRDConfig, path string, opts map[string]st
 	}
 
 	var mClient *minio.Client
-	var err error
+
+	creds, err := newMinioCredentials(opts)
+	if err != nil {
+		return nil, err
+	}
 
 	mOpts := &minio.Options{
-		Creds: credentials.NewStaticV4(
-			opts["minio-keyid"],
-			opts["minio-secret"],
-			"",
-		),
+		Creds:  creds,
 		Secure: minioSsl,
 	}
 	if minioRegion != "" {
@@ -104,7 +107,13 @@ func NewBackendMinio(specs *specs.AniseRDConfig, path string, opts map[string]st
 	ans.MinioClient = mClient
 
 	// Check if the bucket exists
-	found, err := ans.MinioClient.BucketExists(context.Background(), ans.Bucket)
+	found := false
+	err = ans.Retry.Do(context.Background(), "bucket-exists",
+		func(ctx context.Context) error {
+			var e error
+			found, e = ans.MinioClient.BucketExists(ctx, ans.Bucket)
+			return e
+		})
 	if err != nil {
 		return nil, errors.New(
 			fmt.Sprintf("Error on check if the bucket %s: %s", ans.Bucket, err.Error()))
@@ -114,39 +123,87 @@ func NewBackendMinio(specs *specs.AniseRDConfig, path string, opts map[string]st
 		return nil, errors.New(fmt.Sprintf("Bucket %s not found", ans.Bucket))
 	}
 
+	var vConfig minio.BucketVersioningConfiguration
+	err = ans.Retry.Do(context.Background(), "bucket-versioning",
+		func(ctx context.Context) error {
+			var e error
+			vConfig, e = ans.MinioClient.GetBucketVersioning(ctx, ans.Bucket)
+			return e
+		})
+	if err != nil {
+		return nil, errors.New(
+			fmt.Sprintf("Error on retrieve versioning of the bucket %s: %s",
+				ans.Bucket, err.Error()))
+	}
+	ans.Versioned = vConfig.Enabled() || vConfig.Suspended()
+
+	if v, ok := opts["minio-remove-versions"]; ok && v == "true" {
+		ans.RemoveAllVersions = true
+	}
+
 	return ans, nil
 }
 
-func (b *BackendMinio) GetFilesList() ([]string, error) {
+func (b *BackendMinio) GetFilesList(ctx context.Context) ([]string, error) {
 	ans := []string{}
+
+	files, err := b.GetFilesInfo(ctx)
+	if err != nil {
+		return ans, err
+	}
+
+	for _, f := range files {
+		ans = append(ans, f.Name)
+	}
+
+	return ans, nil
+}
+
+func (b *BackendMinio) GetFilesInfo(ctx context.Context) ([]specs.RepoFile, error) {
+	ans := []specs.RepoFile{}
 	opts := minio.ListObjectsOptions{
 		Recursive: true,
 		Prefix:    "",
 	}
 
-	// List all objects from a bucket-name with a matching prefix.
-	for object := range b.MinioClient.ListObjects(context.Background(), b.Bucket, opts) {
-		if object.Err != nil {
-			return ans, errors.New("Error on retrieve list of objects: " + object.Err.Error())
+	err := b.Retry.Do(ctx, "list-objects", func(ctx context.Context) error {
+		ans = []specs.RepoFile{}
+		// List all objects from a bucket-name with a matching prefix.
+		for object := range b.MinioClient.ListObjects(ctx, b.Bucket, opts) {
+			if object.Err != nil {
+				return fmt.Errorf("Error on retrieve list of objects: %w", object.Err)
+			}
+
+			ans = append(ans, specs.RepoFile{
+				Name:         object.Key,
+				Size:         object.Size,
+				LastModified: object.LastModified,
+			})
 		}
+		return nil
+	})
 
-		ans = append(ans, object.Key)
-	}
-
-	return ans, nil
+	return ans, err
 }
 
-func (b *BackendMinio) GetMetadata(file string) (*artifact.PackageArtifact, error) {
+func (b *BackendMinio) GetMetadata(ctx context.Context, file string) (*artifact.PackageArtifact, error) {
 	var outBuffer bytes.Buffer
 
-	object, err := b.MinioClient.GetObject(
-		context.Background(), b.Bucket, file, minio.GetObjectOptions{},
-	)
-	if err != nil {
-		return nil, err
-	}
+	err := b.Retry.Do(ctx, file, func(ctx context.Context) error {
+		outBuffer.Reset()
 
-	if _, err = io.Copy(&outBuffer, object); err != nil {
+		object, err := b.MinioClient.GetObject(
+			ctx, b.Bucket, file, minio.GetObjectOptions{},
+		)
+		if err != nil {
+			return err
+		}
+		defer object.Close()
+
+		_, err = io.Copy(&outBuffer, object)
+		return err
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -155,10 +212,157 @@ func (b *BackendMinio) GetMetadata(file string) (*artifact.PackageArtifact, erro
 	return artifact.NewPackageArtifactFromYaml([]byte(fileContent))
 }
 
-func (b *BackendMinio) CleanFile(file string) error {
+func (b *BackendMinio) DownloadFile(ctx context.Context, file, dst string) error {
+	// FGetObject writes a partial file and renames it to dst only
+	// on success.
+	return b.Retry.DoDownload(ctx, file, func(ctx context.Context) error {
+		return b.MinioClient.FGetObject(ctx, b.Bucket, file, dst,
+			minio.GetObjectOptions{})
+	})
+}
+
+func (b *BackendMinio) CleanFile(ctx context.Context, file string) error {
+	if b.RemoveAllVersions && b.Versioned {
+		res := b.CleanFiles(ctx, []string{file})
+		return res[0].Error
+	}
+
 	opts := minio.RemoveObjectOptions{
 		GovernanceBypass: true,
 	}
-	return b.MinioClient.RemoveObject(context.Background(),
-		b.Bucket, file, opts)
+	return b.Retry.Do(ctx, file, func(ctx context.Context) error {
+		return b.MinioClient.RemoveObject(ctx, b.Bucket, file, opts)
+	})
+}
+
+func (b *BackendMinio) CleanFiles(ctx context.Context, files []string) []specs.CleanResult {
+	ans := []specs.CleanResult{}
+	objects := []minio.ObjectInfo{}
+	mListErrors := make(map[string]error, 0)
+
+	if b.RemoveAllVersions && b.Versioned {
+		// Remove every version and delete marker of the files.
+		versions, err := b.getFilesVersions(ctx, files)
+		if err != nil {
+			for _, f := range files {
+				mListErrors[f] = err
+			}
+		}
+		for _, v := range versions {
+			objects = append(objects, minio.ObjectInfo{
+				Key: v.File, VersionID: v.VersionID, Size: v.Size,
+			})
+		}
+	} else {
+		for _, f := range files {
+			objects = append(objects, minio.ObjectInfo{Key: f})
+		}
+	}
+
+	results := make(map[string]*specs.CleanResult, len(files))
+	for _, f := range files {
+		results[f] = &specs.CleanResult{File: f, Error: mListErrors[f]}
+	}
+
+	if len(objects) > 0 {
+		mErrors, err := b.removeObjects(ctx, objects)
+
+		for _, o := range objects {
+			r := results[o.Key]
+			e, ok := mErrors[objectKey(o)]
+			if !ok {
+				e = err
+				if e == nil {
+					e = errors.New("No result received for the object")
+				}
+			}
+
+			if e != nil {
+				if r.Error == nil {
+					r.Error = e
+				}
+				continue
+			}
+			r.Versions++
+			r.Reclaimed += o.Size
+		}
+	}
+
+	for _, f := range files {
+		r := results[f]
+		if b.RemoveAllVersions && b.Versioned && r.Error == nil && r.Versions == 0 {
+			// The object isn't available in the bucket.
+			r.Error = errors.New(fmt.Sprintf("Object %s not found", f))
+		}
+		ans = append(ans, *r)
+	}
+
+	return ans
+}
+
+func objectKey(o minio.ObjectInfo) string {
+	return o.Key + "@" + o.VersionID
+}
+
+// removeObjects removes the objects with the multi-objects API and
+// returns the error of every object processed. The objects failed with
+// a transient error are retried with a new request until the retries
+// are exhausted.
+func (b *BackendMinio) removeObjects(ctx context.Context, objects []minio.ObjectInfo) (map[string]error, error) {
+	mResults := make(map[string]error, len(objects))
+	pending := objects
+
+	opts := minio.RemoveObjectsOptions{
+		GovernanceBypass: true,
+	}
+
+	err := b.Retry.Do(ctx, "remove-objects", func(ctx context.Context) error {
+		mPending := make(map[string]minio.ObjectInfo, len(pending))
+		for _, o := range pending {
+			mPending[objectKey(o)] = o
+		}
+
+		objectsCh := make(chan minio.ObjectInfo)
+		go func() {
+			defer close(objectsCh)
+			for _, o := range pending {
+				select {
+				case objectsCh <- o:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+
+		var transientErr error
+		retry := []minio.ObjectInfo{}
+		for r := range b.MinioClient.RemoveObjectsWithResult(ctx, b.Bucket, objectsCh, opts) {
+			if r.ObjectName == "" {
+				// Error not related to a specific object.
+				if r.Err != nil {
+					transientErr = r.Err
+				}
+				continue
+			}
+
+			key := r.ObjectName + "@" + r.ObjectVersionID
+			mResults[key] = r.Err
+			if r.Err != nil && IsTransientError(r.Err) {
+				if o, ok := mPending[key]; ok {
+					retry = append(retry, o)
+				}
+				transientErr = r.Err
+			}
+		}
+
+		if transientErr != nil {
+			if len(retry) > 0 {
+				pending = retry
+			}
+			return transientErr
+		}
+		return nil
+	})
+
+	return mResults, err
 }
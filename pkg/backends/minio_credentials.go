@@ -0,0 +1,228 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package backends
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-ini/ini"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"gopkg.in/yaml.v2"
+)
+
+// MinioCredentialsFile is the format of the file defined with
+// the minio-credentials-file option.
+type MinioCredentialsFile struct {
+	AccessKey    string `json:"access_key" yaml:"access_key"`
+	SecretKey    string `json:"secret_key" yaml:"secret_key"`
+	SessionToken string `json:"session_token,omitempty" yaml:"session_token,omitempty"`
+}
+
+func loadMinioCredentialsFile(file string) (*MinioCredentialsFile, error) {
+	ans := &MinioCredentialsFile{}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = yaml.Unmarshal(content, ans); err != nil {
+		return nil, err
+	}
+
+	if ans.AccessKey == "" || ans.SecretKey == "" {
+		return nil, errors.New("access_key and secret_key are mandatory")
+	}
+
+	return ans, nil
+}
+
+// newMinioCredentials creates the chain of the credentials providers.
+// The first provider that returns valid credentials is used:
+//
+//  1. the access key and the secret defined by the options
+//  2. the credentials file defined by the option minio-credentials-file
+//  3. the profile defined by the option minio-aws-profile of the AWS shared
+//     credentials file ($HOME/.aws/credentials) or, if it's not present
+//     there, of the AWS shared config file ($HOME/.aws/config)
+//  4. the minio client config.json alias defined by the option minio-alias
+//  5. the AWS_* environment variables
+//  6. the MINIO_* environment variables, MINIO_ID and MINIO_SECRET included
+//  7. the default profile of the AWS shared credentials file
+//  8. the IAM role of the instance if the option minio-iam is true
+//
+// A profile or an alias explicitly defined must be resolved, otherwise
+// an error is returned instead of using the next providers of the chain.
+func newMinioCredentials(opts map[string]string) (*credentials.Credentials, error) {
+	providers := []credentials.Provider{}
+
+	if opts["minio-keyid"] != "" || opts["minio-secret"] != "" {
+		if opts["minio-keyid"] == "" {
+			return nil, errors.New("Minio key ID is mandatory with the secret access key")
+		}
+		if opts["minio-secret"] == "" {
+			return nil, errors.New("Minio secret Access key is mandatory with the key ID")
+		}
+
+		providers = append(providers, &credentials.Static{
+			Value: credentials.Value{
+				AccessKeyID:     opts["minio-keyid"],
+				SecretAccessKey: opts["minio-secret"],
+				SignerType:      credentials.SignatureV4,
+			},
+		})
+	}
+
+	if file := opts["minio-credentials-file"]; file != "" {
+		cFile, err := loadMinioCredentialsFile(file)
+		if err != nil {
+			return nil, errors.New(
+				fmt.Sprintf("Error on load credentials file %s: %s", file, err.Error()))
+		}
+
+		providers = append(providers, &credentials.Static{
+			Value: credentials.Value{
+				AccessKeyID:     cFile.AccessKey,
+				SecretAccessKey: cFile.SecretKey,
+				SessionToken:    cFile.SessionToken,
+				SignerType:      credentials.SignatureV4,
+			},
+		})
+	}
+
+	if profile := opts["minio-aws-profile"]; profile != "" {
+		p, err := retrieveNamedCredentials(&awsProfileCredentials{
+			CredentialsFile: opts["minio-aws-credentials-file"],
+			ConfigFile:      opts["minio-aws-config-file"],
+			Profile:         profile,
+		})
+		if err != nil {
+			return nil, errors.New(
+				fmt.Sprintf("Error on load AWS profile %s: %s", profile, err.Error()))
+		}
+		providers = append(providers, p)
+	}
+
+	if alias := opts["minio-alias"]; alias != "" {
+		p, err := retrieveNamedCredentials(&credentials.FileMinioClient{
+			Filename: opts["minio-config-file"],
+			Alias:    alias,
+		})
+		if err != nil {
+			return nil, errors.New(
+				fmt.Sprintf("Error on load minio alias %s: %s", alias, err.Error()))
+		}
+		providers = append(providers, p)
+	}
+
+	providers = append(providers,
+		&credentials.EnvAWS{},
+		&credentials.EnvMinio{},
+		// Environment variables supported by the previous releases.
+		&credentials.Static{
+			Value: credentials.Value{
+				AccessKeyID:     os.Getenv("MINIO_ID"),
+				SecretAccessKey: os.Getenv("MINIO_SECRET"),
+				SignerType:      credentials.SignatureV4,
+			},
+		},
+		&credentials.FileAWSCredentials{
+			Filename: opts["minio-aws-credentials-file"],
+		},
+	)
+
+	if opts["minio-iam"] == "true" {
+		providers = append(providers, &credentials.IAM{})
+	}
+
+	ans := credentials.NewChainCredentials(providers)
+
+	v, err := ans.Get()
+	if err != nil {
+		return nil, errors.New("Error on retrieve minio credentials: " + err.Error())
+	}
+	if v.AccessKeyID == "" {
+		return nil, errors.New("No minio credentials found")
+	}
+
+	return ans, nil
+}
+
+// retrieveNamedCredentials checks that the provider of a named profile
+// or alias returns valid credentials.
+func retrieveNamedCredentials(p credentials.Provider) (credentials.Provider, error) {
+	v, err := p.Retrieve()
+	if err != nil {
+		return nil, err
+	}
+	if v.AccessKeyID == "" || v.SecretAccessKey == "" {
+		return nil, errors.New("no credentials defined")
+	}
+	return p, nil
+}
+
+// awsProfileCredentials retrieves the credentials of a profile of the
+// AWS shared credentials file and, if the profile is not defined there,
+// of the AWS shared config file where the section is "profile <name>".
+type awsProfileCredentials struct {
+	CredentialsFile string
+	ConfigFile      string
+	Profile         string
+}
+
+func (p *awsProfileCredentials) Retrieve() (credentials.Value, error) {
+	fileCreds := &credentials.FileAWSCredentials{
+		Filename: p.CredentialsFile,
+		Profile:  p.Profile,
+	}
+	v, err := fileCreds.Retrieve()
+	if err == nil && v.AccessKeyID != "" {
+		return v, nil
+	}
+
+	file := p.ConfigFile
+	if file == "" {
+		file = os.Getenv("AWS_CONFIG_FILE")
+	}
+	if file == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return credentials.Value{}, err
+		}
+		file = filepath.Join(home, ".aws", "config")
+	}
+
+	config, err := ini.Load(file)
+	if err != nil {
+		return credentials.Value{}, err
+	}
+
+	section := "profile " + p.Profile
+	if p.Profile == "default" && !config.HasSection(section) {
+		section = "default"
+	}
+	s, err := config.GetSection(section)
+	if err != nil {
+		return credentials.Value{}, err
+	}
+
+	return credentials.Value{
+		AccessKeyID:     s.Key("aws_access_key_id").String(),
+		SecretAccessKey: s.Key("aws_secret_access_key").String(),
+		SessionToken:    s.Key("aws_session_token").String(),
+		SignerType:      credentials.SignatureV4,
+	}, nil
+}
+
+func (p *awsProfileCredentials) RetrieveWithCredContext(_ *credentials.CredContext) (credentials.Value, error) {
+	return p.Retrieve()
+}
+
+func (p *awsProfileCredentials) IsExpired() bool {
+	return false
+}
@@ -0,0 +1,238 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package backends
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func clearCredentialsEnv(t *testing.T) {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, e := range []string{
+		"AWS_ACCESS_KEY_ID", "AWS_ACCESS_KEY", "AWS_SECRET_ACCESS_KEY",
+		"AWS_SECRET_KEY", "AWS_SESSION_TOKEN", "AWS_PROFILE",
+		"AWS_SHARED_CREDENTIALS_FILE", "MINIO_ACCESS_KEY", "MINIO_SECRET_KEY",
+		"MINIO_ROOT_USER", "MINIO_ROOT_PASSWORD", "MINIO_SHARED_CREDENTIALS_FILE",
+		"MINIO_ALIAS", "MINIO_ID", "MINIO_SECRET", "AWS_CONFIG_FILE",
+	} {
+		t.Setenv(e, "")
+	}
+}
+
+func TestMinioCredentialsNotFound(t *testing.T) {
+	clearCredentialsEnv(t)
+
+	if _, err := newMinioCredentials(map[string]string{}); err == nil {
+		t.Fatal("Expected error without credentials")
+	}
+}
+
+func TestMinioCredentialsStaticFirst(t *testing.T) {
+	clearCredentialsEnv(t)
+	t.Setenv("AWS_ACCESS_KEY_ID", "env-key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "env-secret")
+
+	creds, err := newMinioCredentials(map[string]string{
+		"minio-keyid":  "flag-key",
+		"minio-secret": "flag-secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, _ := creds.Get()
+	if v.AccessKeyID != "flag-key" {
+		t.Fatalf("Expected flag-key, got %s", v.AccessKeyID)
+	}
+}
+
+func TestMinioCredentialsFile(t *testing.T) {
+	clearCredentialsEnv(t)
+	t.Setenv("AWS_ACCESS_KEY_ID", "env-key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "env-secret")
+
+	file := filepath.Join(t.TempDir(), "creds.yaml")
+	err := os.WriteFile(file, []byte("access_key: file-key\nsecret_key: file-secret\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	creds, err := newMinioCredentials(map[string]string{
+		"minio-credentials-file": file,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, _ := creds.Get()
+	if v.AccessKeyID != "file-key" || v.SecretAccessKey != "file-secret" {
+		t.Fatalf("Unexpected credentials %s", v.AccessKeyID)
+	}
+}
+
+func TestMinioCredentialsAwsProfile(t *testing.T) {
+	clearCredentialsEnv(t)
+
+	file := filepath.Join(t.TempDir(), "credentials")
+	err := os.WriteFile(file, []byte(`[default]
+aws_access_key_id = default-key
+aws_secret_access_key = default-secret
+
+[ci]
+aws_access_key_id = ci-key
+aws_secret_access_key = ci-secret
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	creds, err := newMinioCredentials(map[string]string{
+		"minio-aws-profile":          "ci",
+		"minio-aws-credentials-file": file,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, _ := creds.Get()
+	if v.AccessKeyID != "ci-key" {
+		t.Fatalf("Expected ci-key, got %s", v.AccessKeyID)
+	}
+}
+
+func TestMinioCredentialsNamedNotFound(t *testing.T) {
+	clearCredentialsEnv(t)
+	t.Setenv("AWS_ACCESS_KEY_ID", "env-key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "env-secret")
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "credentials")
+	err := os.WriteFile(file, []byte(`[default]
+aws_access_key_id = default-key
+aws_secret_access_key = default-secret
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A missing profile doesn't fall through the environment credentials.
+	_, err = newMinioCredentials(map[string]string{
+		"minio-aws-profile":          "ci",
+		"minio-aws-credentials-file": file,
+	})
+	if err == nil {
+		t.Fatal("Expected error with a missing AWS profile")
+	}
+
+	config := filepath.Join(dir, "config.json")
+	err = os.WriteFile(config, []byte(`{"version": "10", "aliases": {
+  "prod": {"url": "https://s3.example.org", "accessKey": "prod-key",
+    "secretKey": "prod-secret", "api": "S3v4"}}}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = newMinioCredentials(map[string]string{
+		"minio-alias":       "staging",
+		"minio-config-file": config,
+	})
+	if err == nil {
+		t.Fatal("Expected error with a missing minio alias")
+	}
+
+	creds, err := newMinioCredentials(map[string]string{
+		"minio-alias":       "prod",
+		"minio-config-file": config,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, _ := creds.Get()
+	if v.AccessKeyID != "prod-key" {
+		t.Fatalf("Expected prod-key, got %s", v.AccessKeyID)
+	}
+}
+
+func TestMinioCredentialsAwsConfigProfile(t *testing.T) {
+	clearCredentialsEnv(t)
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "credentials")
+	err := os.WriteFile(file, []byte(`[default]
+aws_access_key_id = default-key
+aws_secret_access_key = default-secret
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := filepath.Join(dir, "config")
+	err = os.WriteFile(config, []byte(`[profile ci]
+region = eu-west-1
+aws_access_key_id = ci-key
+aws_secret_access_key = ci-secret
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	creds, err := newMinioCredentials(map[string]string{
+		"minio-aws-profile":          "ci",
+		"minio-aws-credentials-file": file,
+		"minio-aws-config-file":      config,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, _ := creds.Get()
+	if v.AccessKeyID != "ci-key" {
+		t.Fatalf("Expected ci-key, got %s", v.AccessKeyID)
+	}
+}
+
+func TestMinioCredentialsLegacyEnv(t *testing.T) {
+	clearCredentialsEnv(t)
+	t.Setenv("MINIO_ID", "legacy-key")
+
+	config := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(config, []byte(`{"version": "10", "aliases": {
+  "prod": {"url": "https://s3.example.org", "accessKey": "prod-key",
+    "secretKey": "prod-secret", "api": "S3v4"}}}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The alias is used before the environment variables.
+	for _, secret := range []string{"", "legacy-secret"} {
+		t.Setenv("MINIO_SECRET", secret)
+
+		creds, err := newMinioCredentials(map[string]string{
+			"minio-alias":       "prod",
+			"minio-config-file": config,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, _ := creds.Get()
+		if v.AccessKeyID != "prod-key" {
+			t.Fatalf("Expected prod-key, got %s", v.AccessKeyID)
+		}
+	}
+
+	creds, err := newMinioCredentials(map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, _ := creds.Get()
+	if v.AccessKeyID != "legacy-key" {
+		t.Fatalf("Expected legacy-key, got %s", v.AccessKeyID)
+	}
+}
@@ -0,0 +1,290 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package backends
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+type fakeS3Object struct {
+	Key       string `xml:"Key"`
+	VersionID string `xml:"VersionId,omitempty"`
+}
+
+type fakeS3Version struct {
+	VersionID    string
+	Size         int64
+	LastModified time.Time
+	DeleteMarker bool
+}
+
+// fakeS3 is a minimal S3 server that supports the listing of the
+// object versions and the deletion of multiple objects.
+type fakeS3 struct {
+	// The versions of every object, from the oldest to the newest.
+	Objects map[string][]fakeS3Version
+	// Error codes returned on the deletion of an object. Every
+	// deletion consumes a code and an empty code is a success.
+	DeleteErrors map[string][]string
+
+	// The objects received by every deletion request.
+	Deletes [][]fakeS3Object
+	Lists   int
+
+	mutex sync.Mutex
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{
+		Objects:      make(map[string][]fakeS3Version, 0),
+		DeleteErrors: make(map[string][]string, 0),
+		Deletes:      [][]fakeS3Object{},
+	}
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	q := r.URL.Query()
+	switch {
+	case r.Method == http.MethodPost && q.Has("delete"):
+		s.deleteObjects(w, r)
+	case r.Method == http.MethodGet && q.Has("versions"):
+		s.listVersions(w, q.Get("prefix"))
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func (s *fakeS3) deleteObjects(w http.ResponseWriter, r *http.Request) {
+	req := struct {
+		Objects []fakeS3Object `xml:"Object"`
+	}{}
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	s.Deletes = append(s.Deletes, req.Objects)
+
+	var b strings.Builder
+	b.WriteString("<DeleteResult>")
+	for _, o := range req.Objects {
+		code := ""
+		if codes := s.DeleteErrors[o.Key]; len(codes) > 0 {
+			code = codes[0]
+			s.DeleteErrors[o.Key] = codes[1:]
+		}
+
+		if code != "" {
+			fmt.Fprintf(&b, "<Error><Key>%s</Key><VersionId>%s</VersionId>"+
+				"<Code>%s</Code><Message>%s</Message></Error>",
+				o.Key, o.VersionID, code, code)
+			continue
+		}
+
+		versions := []fakeS3Version{}
+		for _, v := range s.Objects[o.Key] {
+			if o.VersionID != "" && v.VersionID != o.VersionID {
+				versions = append(versions, v)
+			}
+		}
+		s.Objects[o.Key] = versions
+
+		fmt.Fprintf(&b, "<Deleted><Key>%s</Key><VersionId>%s</VersionId></Deleted>",
+			o.Key, o.VersionID)
+	}
+	b.WriteString("</DeleteResult>")
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write([]byte(b.String()))
+}
+
+func (s *fakeS3) listVersions(w http.ResponseWriter, prefix string) {
+	s.Lists++
+
+	keys := []string{}
+	for k := range s.Objects {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString("<ListVersionsResult><Name>repo</Name><IsTruncated>false</IsTruncated>")
+	for _, k := range keys {
+		versions := s.Objects[k]
+		// The versions are listed from the newest to the oldest.
+		for i := len(versions) - 1; i >= 0; i-- {
+			v := versions[i]
+			tag := "Version"
+			if v.DeleteMarker {
+				tag = "DeleteMarker"
+			}
+			fmt.Fprintf(&b, "<%s><Key>%s</Key><VersionId>%s</VersionId>"+
+				"<IsLatest>%v</IsLatest><LastModified>%s</LastModified>"+
+				"<Size>%d</Size></%s>",
+				tag, k, v.VersionID, i == len(versions)-1,
+				v.LastModified.UTC().Format(time.RFC3339), v.Size, tag)
+		}
+	}
+	b.WriteString("</ListVersionsResult>")
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write([]byte(b.String()))
+}
+
+func newFakeMinio(t *testing.T, s *fakeS3) *BackendMinio {
+	t.Helper()
+
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+
+	client, err := minio.New(strings.TrimPrefix(srv.URL, "http://"), &minio.Options{
+		Creds:  credentials.NewStaticV4("key", "secret", ""),
+		Secure: false,
+		Region: "us-east-1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &BackendMinio{
+		MinioClient: client,
+		Bucket:      "repo",
+		Retry: &RetryPolicy{
+			Timeout: time.Minute,
+			Retries: 2,
+			Backoff: time.Millisecond,
+		},
+	}
+}
+
+func TestMinioCleanFiles(t *testing.T) {
+	s := newFakeS3()
+	s.DeleteErrors["transient.tar"] = []string{"SlowDown"}
+	s.DeleteErrors["denied.tar"] = []string{"AccessDenied"}
+	b := newFakeMinio(t, s)
+
+	files := []string{"foo.tar", "transient.tar", "denied.tar", "bar.tar"}
+	results := b.CleanFiles(t.Context(), files)
+
+	if len(results) != len(files) {
+		t.Fatalf("Expected %d results, got %d", len(files), len(results))
+	}
+	for idx, r := range results {
+		if r.File != files[idx] {
+			t.Fatalf("Expected the result of %s, got %s", files[idx], r.File)
+		}
+		if (r.Error != nil) != (r.File == "denied.tar") {
+			t.Fatalf("Unexpected result of %s: %v", r.File, r.Error)
+		}
+	}
+
+	// Only the object with the transient error is sent again.
+	if len(s.Deletes) != 2 || len(s.Deletes[0]) != 4 || len(s.Deletes[1]) != 1 ||
+		s.Deletes[1][0].Key != "transient.tar" {
+		t.Fatalf("Unexpected deletion requests %v", s.Deletes)
+	}
+}
+
+func TestMinioCleanFilesRetriesExhausted(t *testing.T) {
+	s := newFakeS3()
+	s.DeleteErrors["slow.tar"] = []string{"SlowDown", "SlowDown", "SlowDown"}
+	b := newFakeMinio(t, s)
+
+	results := b.CleanFiles(t.Context(), []string{"slow.tar", "foo.tar"})
+
+	if results[0].Error == nil || results[1].Error != nil {
+		t.Fatalf("Unexpected results %v", results)
+	}
+	if len(s.Deletes) != 3 {
+		t.Fatalf("Expected 3 deletion requests, got %d", len(s.Deletes))
+	}
+}
+
+func TestMinioCleanFilesAllVersions(t *testing.T) {
+	now := time.Now()
+	s := newFakeS3()
+	s.Objects["foo.tar"] = []fakeS3Version{
+		{VersionID: "f1", Size: 10, LastModified: now.Add(-time.Hour)},
+		{VersionID: "f2", Size: 20, LastModified: now},
+	}
+	s.Objects["bar.tar"] = []fakeS3Version{
+		{VersionID: "b1", Size: 5, LastModified: now.Add(-time.Hour)},
+		{VersionID: "b2", LastModified: now, DeleteMarker: true},
+	}
+	s.Objects["foo.tar.sha256"] = []fakeS3Version{
+		{VersionID: "s1", Size: 1, LastModified: now},
+	}
+	b := newFakeMinio(t, s)
+	b.Versioned = true
+	b.RemoveAllVersions = true
+
+	results := b.CleanFiles(t.Context(), []string{"foo.tar", "bar.tar", "missing.tar"})
+
+	if s.Lists != 1 {
+		t.Fatalf("Expected a single listing of the bucket, got %d", s.Lists)
+	}
+	if r := results[0]; r.File != "foo.tar" || r.Error != nil ||
+		r.Versions != 2 || r.Reclaimed != 30 {
+		t.Fatalf("Unexpected result %+v", r)
+	}
+	if r := results[1]; r.File != "bar.tar" || r.Error != nil ||
+		r.Versions != 2 || r.Reclaimed != 5 {
+		t.Fatalf("Unexpected result %+v", r)
+	}
+	if r := results[2]; r.File != "missing.tar" || r.Error == nil {
+		t.Fatalf("Unexpected result %+v", r)
+	}
+
+	// The object with the same prefix is not touched.
+	if len(s.Objects["foo.tar.sha256"]) != 1 {
+		t.Fatalf("Unexpected removal of foo.tar.sha256")
+	}
+}
+
+func TestMinioPurgeNoncurrentVersions(t *testing.T) {
+	now := time.Now()
+	s := newFakeS3()
+	s.Objects["foo.tar"] = []fakeS3Version{
+		{VersionID: "f1", Size: 10, LastModified: now.Add(-48 * time.Hour)},
+		{VersionID: "f2", Size: 20, LastModified: now.Add(-36 * time.Hour)},
+		{VersionID: "f3", Size: 30, LastModified: now.Add(-time.Hour)},
+	}
+	b := newFakeMinio(t, s)
+	b.Versioned = true
+
+	versions, err := b.GetNoncurrentVersions(t.Context(), now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 1 || versions[0].VersionID != "f1" || versions[0].Size != 10 {
+		t.Fatalf("Unexpected versions %+v", versions)
+	}
+
+	results := b.PurgeVersions(t.Context(), versions)
+	if len(results) != 1 || results[0].Error != nil || results[0].Reclaimed != 10 {
+		t.Fatalf("Unexpected results %+v", results)
+	}
+
+	// The current version is never touched.
+	left := s.Objects["foo.tar"]
+	if len(left) != 2 || left[0].VersionID != "f2" || left[1].VersionID != "f3" {
+		t.Fatalf("Unexpected versions left %+v", left)
+	}
+}
@@ -0,0 +1,115 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package backends
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/macaroni-os/anise-repo-devkit/pkg/specs"
+
+	"github.com/minio/minio-go/v7"
+)
+
+func (b *BackendMinio) IsVersioned() bool { return b.Versioned }
+
+// getVersions returns all versions and delete markers of the objects
+// with the specified prefix. If files is not nil only the versions of
+// the objects present in the map are returned.
+func (b *BackendMinio) getVersions(ctx context.Context, prefix string,
+	files map[string]bool) ([]specs.ObjectVersion, error) {
+	ans := []specs.ObjectVersion{}
+	opts := minio.ListObjectsOptions{
+		Recursive:    true,
+		Prefix:       prefix,
+		WithVersions: true,
+	}
+
+	err := b.Retry.Do(ctx, "list-object-versions", func(ctx context.Context) error {
+		ans = []specs.ObjectVersion{}
+		for object := range b.MinioClient.ListObjects(ctx, b.Bucket, opts) {
+			if object.Err != nil {
+				return fmt.Errorf("Error on retrieve list of object versions: %w",
+					object.Err)
+			}
+
+			if files != nil {
+				if _, ok := files[object.Key]; !ok {
+					continue
+				}
+			}
+
+			ans = append(ans, specs.ObjectVersion{
+				File:           object.Key,
+				VersionID:      object.VersionID,
+				Size:           object.Size,
+				LastModified:   object.LastModified,
+				IsDeleteMarker: object.IsDeleteMarker,
+			})
+		}
+		return nil
+	})
+
+	return ans, err
+}
+
+// getFilesVersions returns all versions and delete markers of the
+// specified files. The bucket is listed once and only the versions of
+// the files are kept.
+func (b *BackendMinio) getFilesVersions(ctx context.Context,
+	files []string) ([]specs.ObjectVersion, error) {
+	mFiles := make(map[string]bool, len(files))
+	for _, f := range files {
+		mFiles[f] = true
+	}
+
+	return b.getVersions(ctx, "", mFiles)
+}
+
+// GetNoncurrentVersions returns the versions that became noncurrent
+// before the specified time.
+func (b *BackendMinio) GetNoncurrentVersions(ctx context.Context, before time.Time) ([]specs.ObjectVersion, error) {
+	versions, err := b.getVersions(ctx, "", nil)
+	if err != nil {
+		return []specs.ObjectVersion{}, err
+	}
+
+	return selectNoncurrentVersions(versions, before), nil
+}
+
+func (b *BackendMinio) PurgeVersions(ctx context.Context, versions []specs.ObjectVersion) []specs.CleanResult {
+	ans := []specs.CleanResult{}
+	objects := []minio.ObjectInfo{}
+
+	for _, v := range versions {
+		objects = append(objects, minio.ObjectInfo{
+			Key: v.File, VersionID: v.VersionID, Size: v.Size,
+		})
+	}
+
+	mErrors, err := b.removeObjects(ctx, objects)
+
+	for _, o := range objects {
+		r := specs.CleanResult{File: o.Key}
+		e, ok := mErrors[objectKey(o)]
+		if !ok {
+			e = err
+			if e == nil {
+				e = fmt.Errorf("No result received for the version %s", o.VersionID)
+			}
+		}
+
+		if e != nil {
+			r.Error = e
+		} else {
+			r.Versions = 1
+			r.Reclaimed = o.Size
+		}
+		ans = append(ans, r)
+	}
+
+	return ans
+}
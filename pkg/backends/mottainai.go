@@ -6,16 +6,22 @@ package backends
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"path"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/macaroni-os/anise-repo-devkit/pkg/specs"
 
 	common "github.com/MottainaiCI/mottainai-server/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	event "github.com/MottainaiCI/mottainai-server/pkg/event"
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
 	utils "github.com/MottainaiCI/mottainai-server/pkg/utils"
 	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
@@ -31,6 +37,9 @@ type BackendMottainai struct {
 	Config          *setting.Config
 	MottainaiClient client.HttpClient
 	Namespace       string
+	Retry           *RetryPolicy
+	// Number of parallel removals executed by CleanFiles.
+	Concurrency int
 }
 
 func setupMottainaiCliConfig(opts map[string]string) (*setting.Config, error) {
@@ -131,6 +140,20 @@ func NewBackendMottainai(specs *specs.AniseRDConfig, path string, opts map[strin
 		return nil, err
 	}
 
+	retry, err := NewRetryPolicy(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	concurrency := 4
+	if v, ok := opts["mottainai-concurrency"]; ok && v != "" {
+		concurrency, err = strconv.Atoi(v)
+		if err != nil || concurrency <= 0 {
+			return nil, errors.New(
+				fmt.Sprintf("Invalid mottainai concurrency value %s", v))
+		}
+	}
+
 	ans := &BackendMottainai{
 		Specs:        specs,
 		ArtefactPath: path,
@@ -140,31 +163,47 @@ func NewBackendMottainai(specs *specs.AniseRDConfig, path string, opts map[strin
 			config.Viper.GetString("apikey"),
 			config,
 		),
-		Namespace: opts["mottainai-namespace"],
+		Namespace:   opts["mottainai-namespace"],
+		Retry:       retry,
+		Concurrency: concurrency,
 	}
 
 	return ans, nil
 }
 
-func (b *BackendMottainai) GetFilesList() ([]string, error) {
+// The mottainai client doesn't support contexts: every request
+// is executed in a goroutine that is abandoned when the context is done.
+
+func (b *BackendMottainai) GetFilesList(ctx context.Context) ([]string, error) {
 	var tlist []string
 	ans := []string{}
 
-	req := &schema.Request{
-		Route:  v1.Schema.GetNamespaceRoute("show_artefacts"),
-		Target: &tlist,
-		Options: map[string]interface{}{
-			":name": b.Namespace,
-		},
-	}
-	err := b.MottainaiClient.Handle(req)
-	if err != nil {
-
-		if req.Response != nil {
-			Error("HTTP CODE: ", req.Response.StatusCode)
-			Error(string(req.ResponseRaw))
-			return ans, err
+	err := b.Retry.Do(ctx, "show-artefacts", func(ctx context.Context) error {
+		var list []string
+		err := runWithContext(ctx, func() error {
+			req := &schema.Request{
+				Route:  v1.Schema.GetNamespaceRoute("show_artefacts"),
+				Target: &list,
+				Options: map[string]interface{}{
+					":name": b.Namespace,
+				},
+			}
+			err := b.MottainaiClient.Handle(req)
+			if err != nil && req.Response != nil {
+				Error("HTTP CODE: ", req.Response.StatusCode)
+				Error(string(req.ResponseRaw))
+				if req.Response.StatusCode >= 500 {
+					return NewTransientError(err)
+				}
+			}
+			return err
+		})
+		if err == nil {
+			tlist = list
 		}
+		return err
+	})
+	if err != nil {
 		return ans, err
 	}
 
@@ -176,14 +215,25 @@ func (b *BackendMottainai) GetFilesList() ([]string, error) {
 	return ans, nil
 }
 
-func (b *BackendMottainai) GetMetadata(file string) (*artifact.PackageArtifact, error) {
+func (b *BackendMottainai) GetMetadata(ctx context.Context, file string) (*artifact.PackageArtifact, error) {
 	var outBuffer bytes.Buffer
 
 	url := b.MottainaiClient.GetBaseURL() +
 		path.Join("/namespace/", b.Namespace, utils.PathEscape(file))
 
-	_, err := b.MottainaiClient.DownloadResource(url, &outBuffer,
-		b.Config.GetAgent().DownloadRateLimit)
+	err := b.Retry.Do(ctx, file, func(ctx context.Context) error {
+		// Use a dedicated buffer to avoid races with abandoned requests.
+		var buf bytes.Buffer
+		err := runWithContext(ctx, func() error {
+			_, err := b.MottainaiClient.DownloadResource(url, &buf,
+				b.Config.GetAgent().DownloadRateLimit)
+			return err
+		})
+		if err == nil {
+			outBuffer = buf
+		}
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -193,9 +243,94 @@ func (b *BackendMottainai) GetMetadata(file string) (*artifact.PackageArtifact,
 	return artifact.NewPackageArtifactFromYaml([]byte(fileContent))
 }
 
-func (b *BackendMottainai) CleanFile(file string) error {
-	_, err := b.MottainaiClient.NamespaceRemovePath(b.Namespace,
-		"/"+file,
-	)
-	return err
+func (b *BackendMottainai) DownloadFile(ctx context.Context, file, dst string) error {
+	url := b.MottainaiClient.GetBaseURL() +
+		path.Join("/namespace/", b.Namespace, utils.PathEscape(file))
+
+	return b.Retry.DoDownload(ctx, file, func(ctx context.Context) error {
+		// Use a dedicated file for every attempt to avoid races with
+		// abandoned requests and rename it only on success.
+		out, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".part-*")
+		if err != nil {
+			return err
+		}
+
+		err = runWithContext(ctx, func() error {
+			_, err := b.MottainaiClient.DownloadResource(url, out,
+				b.Config.GetAgent().DownloadRateLimit)
+			return err
+		})
+		if err == nil {
+			err = out.Close()
+		} else {
+			out.Close()
+		}
+		if err == nil {
+			err = os.Rename(out.Name(), dst)
+		}
+		if err != nil {
+			os.Remove(out.Name())
+		}
+		return err
+	})
+}
+
+func (b *BackendMottainai) CleanFile(ctx context.Context, file string) error {
+	attempts := 0
+	return b.Retry.Do(ctx, file, func(ctx context.Context) error {
+		attempts++
+		retry := attempts > 1
+		return runWithContext(ctx, func() error {
+			resp, err := b.MottainaiClient.NamespaceRemovePath(b.Namespace,
+				"/"+file,
+			)
+			// The request of a previous attempt abandoned on timeout
+			// could be completed and the file already removed.
+			if err != nil && retry && isMottainaiNotFound(resp, err) {
+				return nil
+			}
+			return err
+		})
+	})
+}
+
+// isMottainaiNotFound returns true if the request failed because
+// the file doesn't exist.
+func isMottainaiNotFound(resp event.APIResponse, err error) bool {
+	if resp.Request != nil && resp.Request.Response != nil &&
+		resp.Request.Response.StatusCode == http.StatusNotFound {
+		return true
+	}
+
+	msg := strings.ToLower(resp.Error + " " + err.Error())
+	return strings.Contains(msg, "not found") ||
+		strings.Contains(msg, "no such file")
+}
+
+func (b *BackendMottainai) CleanFiles(ctx context.Context, files []string) []specs.CleanResult {
+	var wg sync.WaitGroup
+
+	ans := make([]specs.CleanResult, len(files))
+	jobs := make(chan int)
+
+	for i := 0; i < b.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				ans[idx] = specs.CleanResult{
+					File:  files[idx],
+					Error: b.CleanFile(ctx, files[idx]),
+				}
+			}
+		}()
+	}
+
+	for idx := range files {
+		jobs <- idx
+	}
+	close(jobs)
+	wg.Wait()
+
+	return ans
 }
@@ -0,0 +1,129 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package backends
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	event "github.com/MottainaiCI/mottainai-server/pkg/event"
+)
+
+// fakeMottainaiClient implements only the removal of the files
+// of a namespace.
+type fakeMottainaiClient struct {
+	client.HttpClient
+
+	// Errors returned on the removal of a file. Every removal
+	// consumes an error and a nil error is a success.
+	Errors  map[string][]error
+	Removed map[string]int
+
+	mutex sync.Mutex
+}
+
+func newFakeMottainaiClient() *fakeMottainaiClient {
+	return &fakeMottainaiClient{
+		Errors:  make(map[string][]error, 0),
+		Removed: make(map[string]int, 0),
+	}
+}
+
+func (c *fakeMottainaiClient) NamespaceRemovePath(id, path string) (event.APIResponse, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if errs := c.Errors[path]; len(errs) > 0 {
+		c.Errors[path] = errs[1:]
+		if errs[0] != nil {
+			return event.APIResponse{}, errs[0]
+		}
+	}
+	c.Removed[path]++
+
+	return event.APIResponse{}, nil
+}
+
+func newFakeMottainai(c *fakeMottainaiClient) *BackendMottainai {
+	return &BackendMottainai{
+		MottainaiClient: c,
+		Namespace:       "repo",
+		Concurrency:     4,
+		Retry: &RetryPolicy{
+			Timeout: time.Minute,
+			Retries: 2,
+			Backoff: time.Millisecond,
+		},
+	}
+}
+
+func TestMottainaiCleanFiles(t *testing.T) {
+	c := newFakeMottainaiClient()
+	c.Errors["/denied-3.tar"] = []error{errors.New("permission denied")}
+	c.Errors["/reset-7.tar"] = []error{NewTransientError(errors.New("connection reset"))}
+	b := newFakeMottainai(c)
+
+	files := []string{}
+	for i := 0; i < 50; i++ {
+		switch i {
+		case 3:
+			files = append(files, "denied-3.tar")
+		case 7:
+			files = append(files, "reset-7.tar")
+		default:
+			files = append(files, fmt.Sprintf("file-%d.tar", i))
+		}
+	}
+
+	results := b.CleanFiles(t.Context(), files)
+
+	if len(results) != len(files) {
+		t.Fatalf("Expected %d results, got %d", len(files), len(results))
+	}
+	for idx, r := range results {
+		if r.File != files[idx] {
+			t.Fatalf("Expected the result of %s, got %s", files[idx], r.File)
+		}
+		if (r.Error != nil) != (r.File == "denied-3.tar") {
+			t.Fatalf("Unexpected result of %s: %v", r.File, r.Error)
+		}
+	}
+
+	// Every file is removed once, the permanent errors are not retried.
+	for _, f := range files {
+		expected := 1
+		if f == "denied-3.tar" {
+			expected = 0
+		}
+		if c.Removed["/"+f] != expected {
+			t.Fatalf("Expected %d removals of %s, got %d", expected, f, c.Removed["/"+f])
+		}
+	}
+}
+
+func TestMottainaiCleanFileRetryNotFound(t *testing.T) {
+	c := newFakeMottainaiClient()
+	b := newFakeMottainai(c)
+
+	// The first request removes the file but it's reported as failed:
+	// the retry doesn't find the file anymore.
+	c.Errors["/foo.tar"] = []error{
+		NewTransientError(errors.New("connection reset")),
+		errors.New("Path not found"),
+	}
+	if err := b.CleanFile(t.Context(), "foo.tar"); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	// A missing file on the first attempt is an error.
+	c.Errors["/bar.tar"] = []error{errors.New("Path not found")}
+	if err := b.CleanFile(t.Context(), "bar.tar"); err == nil {
+		t.Fatal("Expected an error for the missing file")
+	}
+}
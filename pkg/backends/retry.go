@@ -0,0 +1,216 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package backends
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"syscall"
+	"time"
+
+	. "github.com/geaaru/luet/pkg/logger"
+	"github.com/minio/minio-go/v7"
+)
+
+const (
+	DefaultRequestTimeout = 5 * time.Minute
+	DefaultRetries        = 3
+	DefaultRetryBackoff   = time.Second
+	maxRetryBackoff       = 30 * time.Second
+)
+
+// RetryPolicy defines the timeout of every single request to a remote
+// backend and how many times a request that fails with a transient
+// error is retried with exponential backoff.
+type RetryPolicy struct {
+	Timeout time.Duration
+	Retries int
+	Backoff time.Duration
+}
+
+// transientError marks an error that could be resolved retrying
+// the same request.
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string { return e.err.Error() }
+func (e *transientError) Unwrap() error { return e.err }
+
+func NewTransientError(err error) error {
+	return &transientError{err: err}
+}
+
+func NewRetryPolicy(opts map[string]string) (*RetryPolicy, error) {
+	var err error
+
+	ans := &RetryPolicy{
+		Timeout: DefaultRequestTimeout,
+		Retries: DefaultRetries,
+		Backoff: DefaultRetryBackoff,
+	}
+
+	if v, ok := opts["request-timeout"]; ok && v != "" {
+		ans.Timeout, err = time.ParseDuration(v)
+		if err != nil {
+			return nil, errors.New("Invalid request timeout: " + err.Error())
+		}
+	}
+
+	if v, ok := opts["retries"]; ok && v != "" {
+		ans.Retries, err = strconv.Atoi(v)
+		if err != nil || ans.Retries < 0 {
+			return nil, errors.New(fmt.Sprintf("Invalid retries value %s", v))
+		}
+	}
+
+	if v, ok := opts["retry-backoff"]; ok && v != "" {
+		ans.Backoff, err = time.ParseDuration(v)
+		if err != nil {
+			return nil, errors.New("Invalid retry backoff: " + err.Error())
+		}
+	}
+
+	return ans, nil
+}
+
+// Do executes the function fn with a context that expires after the
+// configured timeout. The function is executed again when it fails
+// with a transient error until the retries are exhausted or the
+// parent context is cancelled.
+func (p *RetryPolicy) Do(ctx context.Context, op string, fn func(context.Context) error) error {
+	return p.do(ctx, op, p.Timeout, fn)
+}
+
+// DoDownload executes the function fn like Do but without the timeout
+// of the single request: the download of a big file could require more
+// time and it's stopped only when the parent context is done.
+func (p *RetryPolicy) DoDownload(ctx context.Context, op string, fn func(context.Context) error) error {
+	return p.do(ctx, op, 0, fn)
+}
+
+func (p *RetryPolicy) do(ctx context.Context, op string, timeout time.Duration,
+	fn func(context.Context) error) error {
+	var err error
+
+	backoff := p.Backoff
+	for attempt := 0; ; attempt++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		err = doAttempt(ctx, timeout, fn)
+		if err == nil {
+			return nil
+		}
+
+		// The parent context is done. No retry is possible.
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if attempt >= p.Retries || !IsTransientError(err) {
+			return err
+		}
+
+		Warning(fmt.Sprintf("[%s] Transient error (attempt %d/%d): %s. Retry in %s.",
+			op, attempt+1, p.Retries+1, err.Error(), backoff))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff = nextBackoff(backoff)
+	}
+}
+
+// nextBackoff doubles the backoff up to maxRetryBackoff.
+func nextBackoff(backoff time.Duration) time.Duration {
+	backoff *= 2
+	if backoff > maxRetryBackoff {
+		backoff = maxRetryBackoff
+	}
+	return backoff
+}
+
+func doAttempt(ctx context.Context, timeout time.Duration, fn func(context.Context) error) error {
+	if timeout <= 0 {
+		return fn(ctx)
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return fn(reqCtx)
+}
+
+// IsTransientError returns true if the error is related to a
+// temporary condition of the network or of the remote server.
+func IsTransientError(err error) bool {
+	var tErr *transientError
+	var netErr net.Error
+
+	if err == nil {
+		return false
+	}
+
+	if errors.As(err, &tErr) {
+		return true
+	}
+
+	// Timeout of the single request.
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	if errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	var mErr minio.ErrorResponse
+	if !errors.As(err, &mErr) {
+		return false
+	}
+	if mErr.StatusCode >= 500 || mErr.StatusCode == 429 {
+		return true
+	}
+	switch mErr.Code {
+	case "SlowDown", "RequestTimeout", "InternalError", "ServiceUnavailable":
+		return true
+	}
+
+	return false
+}
+
+// runWithContext executes a function that doesn't support a context
+// and returns when the function ends or the context is done.
+func runWithContext(ctx context.Context, fn func() error) error {
+	done := make(chan error, 1)
+
+	go func() {
+		done <- fn()
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-done:
+		return err
+	}
+}
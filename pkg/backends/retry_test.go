@@ -0,0 +1,165 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package backends
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := &RetryPolicy{
+		Timeout: time.Minute,
+		Retries: 3,
+		Backoff: 10 * time.Millisecond,
+	}
+
+	attempts := []time.Time{}
+	err := p.Do(context.Background(), "request", func(ctx context.Context) error {
+		attempts = append(attempts, time.Now())
+		return NewTransientError(errors.New("connection reset"))
+	})
+	if err == nil {
+		t.Fatal("Expected an error after the last retry")
+	}
+
+	// The first attempt and a new attempt for every retry.
+	if len(attempts) != 4 {
+		t.Fatalf("Expected 4 attempts, got %d", len(attempts))
+	}
+
+	// The backoff doubles after every retry.
+	backoff := p.Backoff
+	for i := 1; i < len(attempts); i++ {
+		if d := attempts[i].Sub(attempts[i-1]); d < backoff {
+			t.Fatalf("Expected a backoff of at least %s before attempt %d, got %s",
+				backoff, i+1, d)
+		}
+		backoff *= 2
+	}
+}
+
+func TestRetryPolicyNextBackoff(t *testing.T) {
+	tests := []struct {
+		backoff  time.Duration
+		expected time.Duration
+	}{
+		{time.Second, 2 * time.Second},
+		{10 * time.Second, 20 * time.Second},
+		{20 * time.Second, maxRetryBackoff},
+		{maxRetryBackoff, maxRetryBackoff},
+	}
+
+	for _, tt := range tests {
+		if got := nextBackoff(tt.backoff); got != tt.expected {
+			t.Fatalf("Expected %s after %s, got %s", tt.expected, tt.backoff, got)
+		}
+	}
+}
+
+func TestRetryPolicyPermanentError(t *testing.T) {
+	p := &RetryPolicy{
+		Timeout: time.Minute,
+		Retries: 3,
+		Backoff: time.Millisecond,
+	}
+
+	attempts := 0
+	err := p.Do(context.Background(), "request", func(ctx context.Context) error {
+		attempts++
+		return errors.New("access denied")
+	})
+	if err == nil || err.Error() != "access denied" {
+		t.Fatalf("Expected the permanent error, got %v", err)
+	}
+	if attempts != 1 {
+		t.Fatalf("Expected 1 attempt, got %d", attempts)
+	}
+
+	// A transient error resolved by a retry.
+	attempts = 0
+	err = p.Do(context.Background(), "request", func(ctx context.Context) error {
+		attempts++
+		if attempts == 1 {
+			return NewTransientError(errors.New("connection reset"))
+		}
+		return nil
+	})
+	if err != nil || attempts != 2 {
+		t.Fatalf("Expected a success after 2 attempts, got %v after %d", err, attempts)
+	}
+}
+
+func TestIsTransientError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"nil", nil, false},
+		{"generic", errors.New("invalid path"), false},
+		{"transient", NewTransientError(errors.New("reset")), true},
+		{"deadline", context.DeadlineExceeded, true},
+		{"wrapped deadline", fmt.Errorf("get: %w", context.DeadlineExceeded), true},
+		{"connection reset", syscall.ECONNRESET, true},
+		{"minio 503", minio.ErrorResponse{StatusCode: 503, Code: "ServiceUnavailable"}, true},
+		{"minio 429", minio.ErrorResponse{StatusCode: 429}, true},
+		{"minio slow down", minio.ErrorResponse{Code: "SlowDown"}, true},
+		{"wrapped minio slow down",
+			fmt.Errorf("put: %w", minio.ErrorResponse{Code: "SlowDown"}), true},
+		{"wrapped minio 500",
+			fmt.Errorf("put: %w", minio.ErrorResponse{StatusCode: 500}), true},
+		{"minio access denied",
+			minio.ErrorResponse{StatusCode: 403, Code: "AccessDenied"}, false},
+		{"wrapped minio not found",
+			fmt.Errorf("get: %w", minio.ErrorResponse{StatusCode: 404, Code: "NoSuchKey"}), false},
+	}
+
+	for _, tt := range tests {
+		if got := IsTransientError(tt.err); got != tt.expected {
+			t.Fatalf("%s: expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestRetryPolicyDownloadWithoutTimeout(t *testing.T) {
+	p := &RetryPolicy{
+		Timeout: 10 * time.Millisecond,
+		Retries: 1,
+		Backoff: time.Millisecond,
+	}
+
+	slow := func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(50 * time.Millisecond):
+			return nil
+		}
+	}
+
+	err := p.Do(context.Background(), "request", slow)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the request timeout, got %v", err)
+	}
+
+	attempts := 0
+	err = p.DoDownload(context.Background(), "download", func(ctx context.Context) error {
+		attempts++
+		return slow(ctx)
+	})
+	if err != nil {
+		t.Fatalf("Unexpected download error: %s", err.Error())
+	}
+	if attempts != 1 {
+		t.Fatalf("Expected 1 attempt, got %d", attempts)
+	}
+}
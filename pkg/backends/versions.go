@@ -0,0 +1,56 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package backends
+
+import (
+	"sort"
+	"time"
+
+	"github.com/macaroni-os/anise-repo-devkit/pkg/specs"
+)
+
+// selectNoncurrentVersions returns the versions that became noncurrent
+// before the specified time. A version becomes noncurrent when the
+// next version of the same object is created, so the current version
+// is never returned. The delete markers without others versions are
+// returned too.
+func selectNoncurrentVersions(versions []specs.ObjectVersion, before time.Time) []specs.ObjectVersion {
+	ans := []specs.ObjectVersion{}
+
+	mObjects := make(map[string][]specs.ObjectVersion, 0)
+	keys := []string{}
+	for _, v := range versions {
+		if _, ok := mObjects[v.File]; !ok {
+			keys = append(keys, v.File)
+		}
+		mObjects[v.File] = append(mObjects[v.File], v)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		list := mObjects[k]
+
+		// Sort from the newest version to the oldest one.
+		sort.SliceStable(list, func(i, j int) bool {
+			return list[i].LastModified.After(list[j].LastModified)
+		})
+
+		purged := 0
+		for i := 1; i < len(list); i++ {
+			if list[i-1].LastModified.Before(before) {
+				ans = append(ans, list[i])
+				purged++
+			}
+		}
+
+		// Drop the delete marker when all previous versions are purged.
+		if list[0].IsDeleteMarker && purged == len(list)-1 &&
+			list[0].LastModified.Before(before) {
+			ans = append(ans, list[0])
+		}
+	}
+
+	return ans
+}
@@ -0,0 +1,85 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package backends
+
+import (
+	"testing"
+	"time"
+
+	"github.com/macaroni-os/anise-repo-devkit/pkg/specs"
+)
+
+func TestSelectNoncurrentVersions(t *testing.T) {
+	now := time.Now()
+	days := func(n int) time.Time { return now.Add(-time.Duration(n) * 24 * time.Hour) }
+	before := days(7)
+
+	for _, tc := range []struct {
+		name     string
+		versions []specs.ObjectVersion
+		expected []string
+	}{
+		{
+			name: "current version only",
+			versions: []specs.ObjectVersion{
+				{File: "a", VersionID: "a1", LastModified: days(30)},
+			},
+			expected: []string{},
+		},
+		{
+			name: "noncurrent before the retention",
+			versions: []specs.ObjectVersion{
+				{File: "a", VersionID: "a3", LastModified: days(1)},
+				{File: "a", VersionID: "a1", LastModified: days(30)},
+				{File: "a", VersionID: "a2", LastModified: days(20)},
+			},
+			// a2 is noncurrent only from 1 day.
+			expected: []string{"a1"},
+		},
+		{
+			name: "old delete marker",
+			versions: []specs.ObjectVersion{
+				{File: "a", VersionID: "a1", LastModified: days(30)},
+				{File: "a", VersionID: "a2", LastModified: days(20), IsDeleteMarker: true},
+			},
+			expected: []string{"a1", "a2"},
+		},
+		{
+			name: "recent delete marker",
+			versions: []specs.ObjectVersion{
+				{File: "a", VersionID: "a1", LastModified: days(30)},
+				{File: "a", VersionID: "a2", LastModified: days(1), IsDeleteMarker: true},
+			},
+			expected: []string{},
+		},
+		{
+			name: "more objects",
+			versions: []specs.ObjectVersion{
+				{File: "a", VersionID: "a1", LastModified: days(30)},
+				{File: "a", VersionID: "a2", LastModified: days(20)},
+				{File: "a", VersionID: "a3", LastModified: days(10), IsDeleteMarker: true},
+				{File: "b", VersionID: "b1", LastModified: days(30)},
+				{File: "b", VersionID: "b2", LastModified: days(3)},
+			},
+			// b1 is noncurrent only from 3 days.
+			expected: []string{"a2", "a1", "a3"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := []string{}
+			for _, v := range selectNoncurrentVersions(tc.versions, before) {
+				got = append(got, v.VersionID)
+			}
+			if len(got) != len(tc.expected) {
+				t.Fatalf("Expected %v, got %v", tc.expected, got)
+			}
+			for idx := range got {
+				if got[idx] != tc.expected[idx] {
+					t.Fatalf("Expected %v, got %v", tc.expected, got)
+				}
+			}
+		})
+	}
+}
@@ -9,8 +9,9 @@ import (
 	"os"
 
 	devkit "github.com/macaroni-os/anise-repo-devkit/pkg/devkit"
-	specs "github.com/macaroni-os/anise-repo-devkit/pkg/specs"
 
+	units "github.com/docker/go-units"
+	isatty "github.com/mattn/go-isatty"
 	cobra "github.com/spf13/cobra"
 )
 
@@ -18,90 +19,41 @@ func NewCleanCommand() *cobra.Command {
 	var cmd = &cobra.Command{
 		Use:   "clean [OPTIONS]",
 		Short: "Clean repository files.",
-		PreRun: func(cmd *cobra.Command, args []string) {
-			treePath, _ := cmd.Flags().GetStringArray("tree")
-
-			if len(treePath) == 0 {
-				fmt.Println("At least one tree path is needed.")
-				os.Exit(1)
-			}
-		},
 		Run: func(cmd *cobra.Command, args []string) {
-			var s *specs.AniseRDConfig
-			var err error
-
-			specsFile, _ := cmd.Flags().GetString("specs-file")
-			backend, _ := cmd.Flags().GetString("backend")
-			path, _ := cmd.Flags().GetString("path")
-			treePath, _ := cmd.Flags().GetStringArray("tree")
 			dryRun, _ := cmd.Flags().GetBool("dry-run")
 			quiet, _ := cmd.Flags().GetBool("quiet")
-			mottainaiProfile, _ := cmd.Flags().GetString("mottainai-profile")
-			mottainaiMaster, _ := cmd.Flags().GetString("mottainai-master")
-			mottainaiApiKey, _ := cmd.Flags().GetString("mottainai-apikey")
-			mottainaiNamespace, _ := cmd.Flags().GetString("mottainai-namespace")
-
-			minioBucket, _ := cmd.Flags().GetString("minio-bucket")
-			minioAccessId, _ := cmd.Flags().GetString("minio-keyid")
-			minioSecret, _ := cmd.Flags().GetString("minio-secret")
-			minioEndpoint, _ := cmd.Flags().GetString("minio-endpoint")
-			minioRegion, _ := cmd.Flags().GetString("minio-region")
-
-			if specsFile == "" {
-				s = specs.NewAniseRDConfig()
-			} else {
-				s, err = specs.LoadSpecsFile(specsFile)
-				if err != nil {
-					fmt.Println("Error on load specs: " + err.Error())
-					os.Exit(1)
-				}
-			}
+			force, _ := cmd.Flags().GetBool("force")
 
-			opts := make(map[string]string, 0)
-			if backend == "mottainai" {
-				if mottainaiProfile != "" {
-					opts["mottainai-profile"] = mottainaiProfile
-				}
-				if mottainaiMaster != "" {
-					opts["mottainai-master"] = mottainaiMaster
-				}
-				if mottainaiApiKey != "" {
-					opts["mottainai-apikey"] = mottainaiApiKey
-				}
-				if mottainaiNamespace != "" {
-					opts["mottainai-namespace"] = mottainaiNamespace
-				}
-			} else if backend == "minio" {
-
-				if minioEndpoint != "" {
-					opts["minio-endpoint"] = minioEndpoint
-				} else {
-					opts["minio-endpoint"] = os.Getenv("MINIO_URL")
-				}
-
-				if minioBucket != "" {
-					opts["minio-bucket"] = minioBucket
-				} else {
-					opts["minio-bucket"] = os.Getenv("MINIO_BUCKET")
-				}
-
-				if minioAccessId != "" {
-					opts["minio-keyid"] = minioAccessId
-				} else {
-					opts["minio-keyid"] = os.Getenv("MINIO_ID")
-				}
+			if err := validateReportFlags(cmd); err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+			setupReportOutput(cmd)
 
-				if minioSecret != "" {
-					opts["minio-secret"] = minioSecret
-				} else {
-					opts["minio-secret"] = os.Getenv("MINIO_SECRET")
-				}
+			setup, err := newRepoSetup(cmd)
+			if err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
 
-				opts["minio-region"] = minioRegion
+			if !hasTrees(cmd, setup) {
+				fmt.Println("At least one tree path or the --repo-trees option is needed.")
+				os.Exit(1)
+			}
 
+			if cmd.Flags().Changed("max-delete-percent") {
+				setup.Specs.Cleaner.MaxDeletePercent, _ = cmd.Flags().GetFloat64("max-delete-percent")
+			}
+			if cmd.Flags().Changed("max-delete") {
+				setup.Specs.Cleaner.MaxDeleteFiles, _ = cmd.Flags().GetInt("max-delete")
+			}
+			if err = setup.Specs.Validate(); err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
 			}
 
-			repoCleaner, err := devkit.NewRepoCleaner(s, backend, path, opts, dryRun)
+			repoCleaner, err := devkit.NewRepoCleaner(setup.Specs,
+				setup.Backend, setup.Path, setup.Opts, dryRun)
 			if err != nil {
 				fmt.Println("Error on initialize repo cleaner: " + err.Error())
 				os.Exit(1)
@@ -111,14 +63,39 @@ func NewCleanCommand() *cobra.Command {
 				repoCleaner.Verbose = true
 			}
 
+			if err = fetchRepositoryTrees(cmd, setup, repoCleaner.RepoKnife); err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+
+			repoCleaner.Force = force
+			if !dryRun {
+				repoCleaner.Journal, err = newJournal(cmd, setup)
+				if err != nil {
+					fmt.Println(err.Error())
+					os.Exit(1)
+				}
+			}
+			if isatty.IsTerminal(os.Stdin.Fd()) && isatty.IsTerminal(os.Stdout.Fd()) {
+				repoCleaner.Confirm = askConfirm
+			}
+
 			// Loading tree in memory
-			err = repoCleaner.LoadTrees(treePath)
+			repoCleaner.BuildTrees = setup.BuildTrees
+			err = repoCleaner.LoadTreeSources(setup.Trees)
 			if err != nil {
 				fmt.Println("Erro on loading trees: " + err.Error())
 				os.Exit(1)
 			}
 
-			err = repoCleaner.Run()
+			err = repoCleaner.Run(cmd.Context())
+			if jErr := repoCleaner.Journal.Close(); jErr != nil {
+				fmt.Println("Error on close journal: " + jErr.Error())
+			}
+			if rErr := writeReport(cmd, repoCleaner.GetReport()); rErr != nil {
+				fmt.Println("Error on write report: " + rErr.Error())
+				os.Exit(1)
+			}
 			if err != nil {
 				fmt.Println("Error on clean repository: " + err.Error())
 				os.Exit(1)
@@ -130,36 +107,36 @@ func NewCleanCommand() *cobra.Command {
 					repoCleaner.ProcessedFiles,
 					len(repoCleaner.Files2Remove),
 				))
+				if size := repoCleaner.GetReport().TotalSize; size > 0 {
+					fmt.Println(fmt.Sprintf("Reclaimable storage: %s.",
+						units.BytesSize(float64(size))))
+				}
 			} else {
 				fmt.Println(fmt.Sprintf(
 					"All done. Processed file %d. Removed files %d.",
 					repoCleaner.ProcessedFiles,
-					len(repoCleaner.Files2Remove),
+					repoCleaner.RemovedFiles,
 				))
+				if repoCleaner.ReclaimedSize > 0 {
+					fmt.Println(fmt.Sprintf("Storage reclaimed: %s.",
+						units.BytesSize(float64(repoCleaner.ReclaimedSize))))
+				}
 			}
 		},
 	}
 
 	var flags = cmd.Flags()
-	flags.StringP("backend", "b", "local", "Select backend repository: local|mottainai.")
-	flags.StringP("path", "p", "", "Path of the repository artefacts.")
+	addBackendFlags(flags)
+	addRepoTreesFlag(flags)
 	flags.Bool("dry-run", false, "Only check files to remove.")
 	flags.Bool("quiet", false, "Quiet output.")
-	flags.String("mottainai-profile", "", "Set mottainai profile to use.")
-	flags.String("mottainai-master", "", "Set mottainai Server to use.")
-	flags.String("mottainai-apikey", "", "Set mottainai API Key to use.")
-	flags.String("mottainai-namespace", "", "Set mottainai namespace to use.")
-
-	// Minio options
-	flags.String("minio-bucket", "",
-		"Set minio bucket to use or set env MINIO_BUCKET.")
-	flags.String("minio-endpoint", "",
-		"Set minio endpoint to use or set env MINIO_URL.")
-	flags.String("minio-keyid", "",
-		"Set minio Access Key to use or set env MINIO_ID.")
-	flags.String("minio-secret", "",
-		"Set minio Access Key to use or set env MINIO_SECRET.")
-	flags.String("minio-region", "", "Optinally define the minio region.")
+	flags.Bool("force", false,
+		"Remove the files also if the safety limits are exceeded.")
+	flags.Float64("max-delete-percent", 0,
+		"Max percentage of the repository files to remove (default 50).")
+	flags.Int("max-delete", 0,
+		"Max number of files to remove. 0 means no limit.")
+	addReportFlags(flags)
 
 	return cmd
 }
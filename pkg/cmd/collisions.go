@@ -0,0 +1,83 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	devkit "github.com/macaroni-os/anise-repo-devkit/pkg/devkit"
+
+	cobra "github.com/spf13/cobra"
+)
+
+func NewCollisionsCommand() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "collisions [OPTIONS]",
+		Short: "Show the files shipped by multiple packages.",
+		Long: `Search the files shipped by multiple packages that don't declare
+a conflict between them. The command exits with an error if
+collisions are found.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			jsonOutput, _ := cmd.Flags().GetBool("json")
+			allVersions, _ := cmd.Flags().GetBool("all-versions")
+			allowed, _ := cmd.Flags().GetStringArray("allow")
+
+			setup, err := newRepoSetup(cmd)
+			if err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+
+			setup.Specs.Collisions.AllowedPaths = append(
+				setup.Specs.Collisions.AllowedPaths, allowed...)
+			if err = setup.Specs.Validate(); err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+
+			knife, err := devkit.NewRepoKnife(setup.Specs,
+				setup.Backend, setup.Path, setup.Opts)
+			if err != nil {
+				fmt.Println("Error on initialize repo knife: " + err.Error())
+				os.Exit(1)
+			}
+			knife.SetCache(newMetadataCache(cmd, setup))
+
+			collisions, err := knife.SearchCollisions(cmd.Context(), allVersions)
+			if err != nil {
+				fmt.Println("Error on search collisions: " + err.Error())
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				data, _ := json.Marshal(collisions)
+				fmt.Println(string(data))
+			} else {
+				for _, c := range collisions {
+					fmt.Println(fmt.Sprintf("%s: %s <-> %s",
+						c.File, c.Package1, c.Package2))
+				}
+				fmt.Println(fmt.Sprintf("Found %d collisions.", len(collisions)))
+			}
+
+			if len(collisions) > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+
+	var flags = cmd.Flags()
+	addBackendFlags(flags)
+	addCacheFlags(flags)
+	flags.Bool("all-versions", false,
+		"Check all versions of the packages and not only the latest.")
+	flags.StringArray("allow", []string{},
+		"Regex of the paths that could be shipped by multiple packages.")
+	flags.Bool("json", false, "Show the collisions in JSON format.")
+
+	return cmd
+}
@@ -0,0 +1,396 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	devkit "github.com/macaroni-os/anise-repo-devkit/pkg/devkit"
+	specs "github.com/macaroni-os/anise-repo-devkit/pkg/specs"
+
+	cobra "github.com/spf13/cobra"
+	pflag "github.com/spf13/pflag"
+)
+
+// repoSetup contains the backend and trees configuration resolved
+// from the specs file, the selected repository and the CLI flags.
+type repoSetup struct {
+	Specs     *specs.AniseRDConfig
+	Backend   string
+	Path      string
+	Opts      map[string]string
+	TreePaths []string
+	SpecsFile string
+	// The trees resolved from the tree sources. TreePaths contains
+	// their local paths.
+	Trees []*devkit.TreeSource
+	// The trees with the compiler definitions. If empty the
+	// trees are used.
+	BuildTrees []*devkit.TreeSource
+}
+
+// GetBuildTreePaths returns the local paths of the build trees.
+func (s *repoSetup) GetBuildTreePaths() []string {
+	if len(s.BuildTrees) > 0 {
+		return devkit.GetTreesPaths(s.BuildTrees)
+	}
+	return s.TreePaths
+}
+
+var (
+	mottainaiOptions = []string{
+		"mottainai-profile",
+		"mottainai-master",
+		"mottainai-apikey",
+		"mottainai-namespace",
+		"mottainai-concurrency",
+	}
+
+	minioOptions = []string{
+		"minio-bucket",
+		"minio-endpoint",
+		"minio-keyid",
+		"minio-secret",
+		"minio-region",
+		"minio-credentials-file",
+		"minio-aws-profile",
+		"minio-aws-credentials-file",
+		"minio-aws-config-file",
+		"minio-alias",
+		"minio-config-file",
+	}
+
+	// Options valid for all remote backends.
+	retryOptions = []string{
+		"request-timeout",
+		"retries",
+		"retry-backoff",
+	}
+
+	minioEnvs = map[string]string{
+		"minio-endpoint": "MINIO_URL",
+		"minio-bucket":   "MINIO_BUCKET",
+	}
+)
+
+func addBackendFlags(flags *pflag.FlagSet) {
+	flags.StringP("backend", "b", "local", "Select backend repository: local|mottainai|minio.")
+	flags.StringP("path", "p", "", "Path of the repository artefacts.")
+	flags.String("mottainai-profile", "", "Set mottainai profile to use.")
+	flags.String("mottainai-master", "", "Set mottainai Server to use.")
+	flags.String("mottainai-apikey", "", "Set mottainai API Key to use.")
+	flags.String("mottainai-namespace", "", "Set mottainai namespace to use.")
+	flags.String("mottainai-concurrency", "",
+		"Number of parallel removals on mottainai backend (default 4).")
+
+	// Minio options
+	flags.String("minio-bucket", "",
+		"Set minio bucket to use or set env MINIO_BUCKET.")
+	flags.String("minio-endpoint", "",
+		"Set minio endpoint to use or set env MINIO_URL.")
+	flags.String("minio-keyid", "",
+		"Set minio Access Key to use or set env MINIO_ID.")
+	flags.String("minio-secret", "",
+		"Set minio Access Key to use or set env MINIO_SECRET.")
+	flags.String("minio-region", "", "Optinally define the minio region.")
+	flags.String("minio-credentials-file", "",
+		"Path of a YAML file with the access_key and secret_key of minio.")
+	flags.String("minio-aws-profile", "",
+		"Use the credentials of the profile of the AWS shared credentials or config file.")
+	flags.String("minio-aws-credentials-file", "",
+		"Path of the AWS shared credentials file. Default $HOME/.aws/credentials.")
+	flags.String("minio-aws-config-file", "",
+		"Path of the AWS shared config file. Default $HOME/.aws/config.")
+	flags.String("minio-alias", "",
+		"Use the credentials of the alias of the minio client config.json.")
+	flags.String("minio-config-file", "",
+		"Path of the minio client config.json. Default $HOME/.mc/config.json.")
+	flags.Bool("minio-iam", false,
+		"Retrieve the credentials from the IAM role of the instance.")
+	flags.Bool("minio-remove-versions", false,
+		"Remove all versions of the files removed from a versioned bucket.")
+
+	// Remote backends options
+	flags.String("request-timeout", "",
+		"Timeout of every request to the remote backend, downloads excluded (default 5m).")
+	flags.String("retries", "",
+		"Number of retries of a request that fails with a transient error (default 3).")
+	flags.String("retry-backoff", "",
+		"Initial wait time before retry a failed request. It's doubled on every retry (default 1s).")
+}
+
+// newRepoSetup loads the specs file and resolves the backend options.
+// The values of the flags explicitly set have the precedence over the
+// values of the repository selected with --repo.
+func newRepoSetup(cmd *cobra.Command) (*repoSetup, error) {
+	var err error
+	var repo *specs.AniseRDRepository
+
+	specsFile, _ := cmd.Flags().GetString("specs-file")
+	repoName, _ := cmd.Flags().GetString("repo")
+	treePath, _ := cmd.Flags().GetStringArray("tree")
+	buildTreePath, _ := cmd.Flags().GetStringArray("build-tree")
+
+	ans := &repoSetup{
+		Opts:      make(map[string]string, 0),
+		TreePaths: treePath,
+		SpecsFile: specsFile,
+	}
+
+	if specsFile == "" {
+		if repoName != "" {
+			return nil, errors.New("The --repo option requires a specs file.")
+		}
+		ans.Specs = specs.NewAniseRDConfig()
+	} else {
+		ans.Specs, err = specs.LoadSpecsFile(specsFile)
+		if err != nil {
+			return nil, errors.New("Error on load specs: " + err.Error())
+		}
+	}
+
+	ans.Backend, _ = cmd.Flags().GetString("backend")
+	ans.Path, _ = cmd.Flags().GetString("path")
+
+	if repoName != "" {
+		repo, err = ans.Specs.GetRepository(repoName)
+		if err != nil {
+			return nil, err
+		}
+
+		ans.Specs.ApplyRepository(repo)
+		ans.Opts = repo.GetOptions()
+
+		if !cmd.Flags().Changed("backend") && repo.Backend != "" {
+			ans.Backend = repo.Backend
+		}
+		if !cmd.Flags().Changed("path") && repo.Path != "" {
+			ans.Path = repo.Path
+		}
+		if len(ans.TreePaths) == 0 {
+			ans.TreePaths = repo.TreePaths
+		}
+		if len(buildTreePath) == 0 {
+			buildTreePath = repo.BuildTreePaths
+		}
+	}
+
+	if len(ans.TreePaths) > 0 {
+		ans.Trees, err = devkit.ResolveTrees(cmd.Context(), ans.TreePaths,
+			getTreesCacheDir(cmd))
+		if err != nil {
+			return nil, err
+		}
+		ans.TreePaths = devkit.GetTreesPaths(ans.Trees)
+	}
+
+	if len(buildTreePath) > 0 {
+		ans.BuildTrees, err = devkit.ResolveTrees(cmd.Context(), buildTreePath,
+			getTreesCacheDir(cmd))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, o := range retryOptions {
+		if v, _ := cmd.Flags().GetString(o); v != "" {
+			ans.Opts[o] = v
+		}
+	}
+
+	if ans.Backend == "mottainai" {
+		for _, o := range mottainaiOptions {
+			if v, _ := cmd.Flags().GetString(o); v != "" {
+				ans.Opts[o] = v
+			}
+		}
+	} else if ans.Backend == "minio" {
+		for _, o := range minioOptions {
+			if v, _ := cmd.Flags().GetString(o); v != "" {
+				ans.Opts[o] = v
+			} else if _, ok := ans.Opts[o]; !ok {
+				if env, ok := minioEnvs[o]; ok {
+					ans.Opts[o] = os.Getenv(env)
+				}
+			}
+		}
+
+		for _, o := range []string{"minio-iam", "minio-remove-versions"} {
+			if cmd.Flags().Changed(o) {
+				v, _ := cmd.Flags().GetBool(o)
+				ans.Opts[o] = strconv.FormatBool(v)
+			}
+		}
+	}
+
+	return ans, nil
+}
+
+func getTreesCacheDir(cmd *cobra.Command) string {
+	if dir, _ := cmd.Flags().GetString("trees-cache-dir"); dir != "" {
+		return dir
+	}
+	return devkit.DefaultTreesCacheDir()
+}
+
+func addRepoTreesFlag(flags *pflag.FlagSet) {
+	flags.Bool("repo-trees", false,
+		"Load the runtime and build trees published in the repository (tree.tar and compilertree.tar).")
+}
+
+// hasTrees returns true if the trees are defined or the trees
+// published in the repository are requested.
+func hasTrees(cmd *cobra.Command, setup *repoSetup) bool {
+	repoTrees, _ := cmd.Flags().GetBool("repo-trees")
+	return repoTrees || len(setup.TreePaths) > 0
+}
+
+// fetchRepositoryTrees adds to the setup the trees published in the
+// repository when the --repo-trees option is enabled.
+func fetchRepositoryTrees(cmd *cobra.Command, setup *repoSetup, knife *devkit.RepoKnife) error {
+	if repoTrees, _ := cmd.Flags().GetBool("repo-trees"); !repoTrees {
+		return nil
+	}
+
+	runtime, build, err := knife.FetchRepositoryTrees(cmd.Context(), getTreesCacheDir(cmd))
+	if err != nil {
+		return errors.New("Error on fetch repository trees: " + err.Error())
+	}
+
+	if len(setup.BuildTrees) == 0 {
+		// The trees defined with --tree are used also as build trees.
+		setup.BuildTrees = append(setup.BuildTrees, setup.Trees...)
+	}
+	setup.Trees = append(setup.Trees, runtime)
+	setup.TreePaths = devkit.GetTreesPaths(setup.Trees)
+	setup.BuildTrees = append(setup.BuildTrees, build)
+
+	return nil
+}
+
+// askConfirm asks a confirmation to the user on the terminal.
+func askConfirm(msg string) bool {
+	fmt.Printf("%s [y/N]: ", msg)
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// getJournalFile returns the journal path defined by the flag
+// --journal-file or by the specs.
+func getJournalFile(cmd *cobra.Command, s *specs.AniseRDConfig) string {
+	if f, _ := cmd.Flags().GetString("journal-file"); f != "" {
+		return f
+	}
+	return s.GetJournalFile()
+}
+
+// newJournal creates the journal of the write operations of the command
+// and checks that the journal is writable.
+func newJournal(cmd *cobra.Command, setup *repoSetup) (*devkit.Journal, error) {
+	run, err := devkit.NewJournalRun(cmd.Name(), setup.Backend, setup.Path,
+		setup.SpecsFile, setup.Trees, setup.BuildTrees)
+	if err != nil {
+		return nil, err
+	}
+
+	ans := devkit.NewJournal(getJournalFile(cmd, setup.Specs), *run)
+	if err = ans.Check(); err != nil {
+		return nil, errors.New("Error on open journal: " + err.Error())
+	}
+
+	return ans, nil
+}
+
+func addCacheFlags(flags *pflag.FlagSet) {
+	flags.String("cache-dir", "",
+		"Directory of the metadata cache (default $XDG_CACHE_HOME/anise-repo-devkit).")
+	flags.Bool("no-cache", false,
+		"Disable the metadata cache. It's always disabled with the mottainai backend.")
+}
+
+// newMetadataCache returns the metadata cache of the repository
+// or nil if the cache is disabled.
+func newMetadataCache(cmd *cobra.Command, setup *repoSetup) *devkit.MetadataCache {
+	if noCache, _ := cmd.Flags().GetBool("no-cache"); noCache {
+		return nil
+	}
+
+	dir, _ := cmd.Flags().GetString("cache-dir")
+	if dir == "" {
+		dir = devkit.DefaultCacheDir()
+	}
+
+	// Identify the repository of the backend.
+	namespace := strings.Join([]string{
+		setup.Backend, setup.Path,
+		setup.Opts["minio-endpoint"], setup.Opts["minio-bucket"],
+		setup.Opts["mottainai-master"], setup.Opts["mottainai-namespace"],
+	}, "|")
+
+	return devkit.NewMetadataCache(dir, namespace)
+}
+
+func addReportFlags(flags *pflag.FlagSet) {
+	flags.String("report-file", "",
+		"Write the report of the files removed to the file. Use - for stdout (logs on stderr).")
+	flags.String("report-format", devkit.ReportFormatJson,
+		"Format of the report: json|yaml|markdown.")
+}
+
+func validateReportFlags(cmd *cobra.Command) error {
+	format, _ := cmd.Flags().GetString("report-format")
+	switch format {
+	case devkit.ReportFormatJson, devkit.ReportFormatYaml, devkit.ReportFormatMarkdown:
+		return nil
+	default:
+		return errors.New(fmt.Sprintf("Invalid report format %s", format))
+	}
+}
+
+// reportStdout is the standard output where the report is written
+// with --report-file -.
+var reportStdout = os.Stdout
+
+// setupReportOutput moves the logs to the standard error when the
+// report is written to the standard output.
+func setupReportOutput(cmd *cobra.Command) {
+	if file, _ := cmd.Flags().GetString("report-file"); file == "-" {
+		reportStdout = os.Stdout
+		os.Stdout = os.Stderr
+	}
+}
+
+// writeReport writes the report if the flag --report-file is defined.
+func writeReport(cmd *cobra.Command, report *devkit.Report) error {
+	file, _ := cmd.Flags().GetString("report-file")
+	format, _ := cmd.Flags().GetString("report-format")
+
+	if file == "" {
+		return nil
+	}
+
+	if file == "-" {
+		return report.Write(reportStdout, format)
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return report.Write(f, format)
+}
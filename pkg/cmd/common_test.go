@@ -0,0 +1,115 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+const setupSpecs = `
+repositories:
+  - name: main
+    backend: minio
+    path: /srv/main
+    options:
+      minio-bucket: main-bucket
+      minio-endpoint: s3.example.org
+`
+
+func newSetupCommand(t *testing.T, args ...string) *cobra.Command {
+	t.Helper()
+
+	cmd := &cobra.Command{Use: "test"}
+	flags := cmd.Flags()
+	flags.String("specs-file", "", "")
+	flags.String("repo", "", "")
+	flags.StringArray("tree", []string{}, "")
+	flags.StringArray("build-tree", []string{}, "")
+	addBackendFlags(flags)
+
+	if err := cmd.ParseFlags(args); err != nil {
+		t.Fatal(err)
+	}
+
+	return cmd
+}
+
+func TestNewRepoSetup(t *testing.T) {
+	specsFile := filepath.Join(t.TempDir(), "specs.yaml")
+	if err := os.WriteFile(specsFile, []byte(setupSpecs), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("MINIO_URL", "env.example.org")
+	t.Setenv("MINIO_BUCKET", "env-bucket")
+	t.Setenv("MINIO_ID", "env-id")
+	t.Setenv("MINIO_SECRET", "env-secret")
+
+	tests := []struct {
+		name     string
+		args     []string
+		backend  string
+		path     string
+		bucket   string
+		endpoint string
+	}{
+		{
+			"repository values",
+			[]string{"--specs-file", specsFile, "--repo", "main"},
+			"minio", "/srv/main", "main-bucket", "s3.example.org",
+		},
+		{
+			"explicit flags",
+			[]string{"--specs-file", specsFile, "--repo", "main",
+				"--path", "/srv/other", "--minio-bucket", "flag-bucket"},
+			"minio", "/srv/other", "flag-bucket", "s3.example.org",
+		},
+		{
+			"explicit backend",
+			[]string{"--specs-file", specsFile, "--repo", "main",
+				"--backend", "local"},
+			"local", "/srv/main", "main-bucket", "s3.example.org",
+		},
+		{
+			"environment",
+			[]string{"--backend", "minio", "--path", "/srv/env"},
+			"minio", "/srv/env", "env-bucket", "env.example.org",
+		},
+	}
+
+	for _, tt := range tests {
+		setup, err := newRepoSetup(newSetupCommand(t, tt.args...))
+		if err != nil {
+			t.Fatalf("%s: unexpected error %s", tt.name, err.Error())
+		}
+
+		if setup.Backend != tt.backend || setup.Path != tt.path {
+			t.Fatalf("%s: unexpected backend %s with path %s", tt.name,
+				setup.Backend, setup.Path)
+		}
+		if setup.Opts["minio-bucket"] != tt.bucket ||
+			setup.Opts["minio-endpoint"] != tt.endpoint {
+			t.Fatalf("%s: unexpected options %v", tt.name, setup.Opts)
+		}
+
+		// The credentials of the environment are resolved by the
+		// credentials chain of the backend.
+		if _, ok := setup.Opts["minio-keyid"]; ok {
+			t.Fatalf("%s: unexpected minio-keyid option", tt.name)
+		}
+		if _, ok := setup.Opts["minio-secret"]; ok {
+			t.Fatalf("%s: unexpected minio-secret option", tt.name)
+		}
+	}
+
+	// A repository requires the specs file.
+	if _, err := newRepoSetup(newSetupCommand(t, "--repo", "main")); err == nil {
+		t.Fatal("Expected an error without the specs file")
+	}
+}
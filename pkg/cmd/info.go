@@ -0,0 +1,160 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	devkit "github.com/macaroni-os/anise-repo-devkit/pkg/devkit"
+
+	units "github.com/docker/go-units"
+	cobra "github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+func printArtifactInfo(info *devkit.ArtifactInfo) {
+	printMap := func(title string, m map[string]string) {
+		if len(m) == 0 {
+			return
+		}
+		keys := []string{}
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		fmt.Println(title + ":")
+		for _, k := range keys {
+			fmt.Println(fmt.Sprintf("  %s: %s", k, m[k]))
+		}
+	}
+	printList := func(title string, l []string) {
+		if len(l) == 0 {
+			return
+		}
+		fmt.Println(title + ":")
+		for _, e := range l {
+			fmt.Println("  - " + e)
+		}
+	}
+
+	fmt.Println(fmt.Sprintf("Package: %s", info.Package))
+	fmt.Println(fmt.Sprintf("Metadata: %s", info.MetaFile))
+	fmt.Println(fmt.Sprintf("Path: %s", info.Path))
+	if info.Size > 0 {
+		fmt.Println(fmt.Sprintf("Size: %s", units.BytesSize(float64(info.Size))))
+	}
+	fmt.Println(fmt.Sprintf("Compression: %s", info.CompressionType))
+	if info.BuildTimestamp != "" {
+		fmt.Println(fmt.Sprintf("Build timestamp: %s", info.BuildTimestamp))
+	}
+	fmt.Println(fmt.Sprintf("Files: %d", info.Files))
+	if info.TreeDefinition != "" {
+		fmt.Println(fmt.Sprintf("Tree definition: %s", info.TreeDefinition))
+	}
+	printMap("Checksums", info.Checksums)
+	printList("Requires", info.Requires)
+	printList("Conflicts", info.Conflicts)
+	printList("Provides", info.Provides)
+	printMap("Labels", info.Labels)
+
+	if len(info.Annotations) > 0 {
+		data, _ := yaml.Marshal(info.Annotations)
+		fmt.Println("Annotations:")
+		for _, l := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+			fmt.Println("  " + l)
+		}
+	}
+}
+
+func NewInfoCommand() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "info <category/name[-version]> [OPTIONS]",
+		Short: "Show the artifacts of a package.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			output, _ := cmd.Flags().GetString("output")
+
+			switch output {
+			case "text", "json", "yaml":
+			default:
+				fmt.Println("Invalid output format " + output)
+				os.Exit(1)
+			}
+
+			pkg, err := devkit.ParsePackageStr(args[0])
+			if err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+
+			setup, err := newRepoSetup(cmd)
+			if err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+
+			knife, err := devkit.NewRepoKnife(setup.Specs,
+				setup.Backend, setup.Path, setup.Opts)
+			if err != nil {
+				fmt.Println("Error on initialize repo knife: " + err.Error())
+				os.Exit(1)
+			}
+			knife.SetCache(newMetadataCache(cmd, setup))
+
+			if err = fetchRepositoryTrees(cmd, setup, knife); err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+
+			if len(setup.Trees) > 0 {
+				err = knife.LoadTreeSources(setup.Trees)
+				if err != nil {
+					fmt.Println("Erro on loading trees: " + err.Error())
+					os.Exit(1)
+				}
+			}
+
+			infos, err := knife.GetArtifactsInfo(cmd.Context(), pkg)
+			if err != nil {
+				fmt.Println("Error on retrieve artifacts: " + err.Error())
+				os.Exit(1)
+			}
+
+			if len(infos) == 0 {
+				fmt.Println(fmt.Sprintf("No artifacts found for %s.", args[0]))
+				os.Exit(1)
+			}
+
+			switch output {
+			case "json":
+				data, _ := json.Marshal(infos)
+				fmt.Println(string(data))
+			case "yaml":
+				data, _ := yaml.Marshal(infos)
+				fmt.Print(string(data))
+			default:
+				for idx, info := range infos {
+					if idx > 0 {
+						fmt.Println("")
+					}
+					printArtifactInfo(info)
+				}
+			}
+		},
+	}
+
+	var flags = cmd.Flags()
+	addBackendFlags(flags)
+	addRepoTreesFlag(flags)
+	addCacheFlags(flags)
+	flags.StringP("output", "o", "text", "Output format: text|json|yaml.")
+
+	return cmd
+}
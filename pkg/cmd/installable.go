@@ -0,0 +1,110 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	devkit "github.com/macaroni-os/anise-repo-devkit/pkg/devkit"
+
+	anise_pkg "github.com/geaaru/luet/pkg/package"
+	cobra "github.com/spf13/cobra"
+)
+
+func NewCheckInstallableCommand() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "check-installable [category/name[-version]...] [OPTIONS]",
+		Short: "Check that the packages are installable from the repository.",
+		Long: `Load all artifacts of the repository in a package database and
+solve every package alone. Without packages the latest version of every
+package is checked. With --together the packages are solved as a single
+set. The command exits with an error if a package is not installable.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			together, _ := cmd.Flags().GetBool("together")
+			jsonOutput, _ := cmd.Flags().GetBool("json")
+
+			targets := []*anise_pkg.DefaultPackage{}
+			for _, a := range args {
+				p, err := devkit.ParsePackageStr(a)
+				if err != nil {
+					fmt.Println(err.Error())
+					os.Exit(1)
+				}
+				targets = append(targets, p)
+			}
+
+			if together && len(targets) == 0 {
+				fmt.Println("The --together option requires the packages to check.")
+				os.Exit(1)
+			}
+
+			setup, err := newRepoSetup(cmd)
+			if err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+
+			knife, err := devkit.NewRepoKnife(setup.Specs,
+				setup.Backend, setup.Path, setup.Opts)
+			if err != nil {
+				fmt.Println("Error on initialize repo knife: " + err.Error())
+				os.Exit(1)
+			}
+			knife.SetCache(newMetadataCache(cmd, setup))
+
+			checks, err := knife.CheckInstallable(cmd.Context(), targets, together)
+			if err != nil {
+				fmt.Println("Error on check packages: " + err.Error())
+				os.Exit(1)
+			}
+
+			failed := 0
+			for _, c := range checks {
+				if !c.Installable {
+					failed++
+				}
+			}
+
+			if jsonOutput {
+				data, _ := json.Marshal(checks)
+				fmt.Println(string(data))
+			} else {
+				for _, c := range checks {
+					if c.Installable {
+						continue
+					}
+
+					fmt.Println(fmt.Sprintf("[%s] Not installable.",
+						strings.Join(c.Packages, ", ")))
+					for _, m := range c.Missing {
+						fmt.Println("  - missing: " + m)
+					}
+					if c.Explanation != "" {
+						for _, l := range strings.Split(c.Explanation, "\n") {
+							fmt.Println("    " + l)
+						}
+					}
+				}
+				fmt.Println(fmt.Sprintf("Checked %d. Not installable %d.",
+					len(checks), failed))
+			}
+
+			if failed > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+
+	var flags = cmd.Flags()
+	addBackendFlags(flags)
+	addCacheFlags(flags)
+	flags.Bool("together", false, "Solve the packages as a single set.")
+	flags.Bool("json", false, "Show the results in JSON format.")
+
+	return cmd
+}
@@ -0,0 +1,133 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	devkit "github.com/macaroni-os/anise-repo-devkit/pkg/devkit"
+	specs "github.com/macaroni-os/anise-repo-devkit/pkg/specs"
+
+	units "github.com/docker/go-units"
+	cobra "github.com/spf13/cobra"
+)
+
+// parseJournalDate parses a date in the format YYYY-MM-DD or RFC3339.
+func parseJournalDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return t, errors.New(fmt.Sprintf("Invalid date %s", s))
+	}
+	return t, nil
+}
+
+func NewJournalCommand() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "journal [OPTIONS]",
+		Short: "Show the journal of the write operations.",
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+
+			specsFile, _ := cmd.Flags().GetString("specs-file")
+			since, _ := cmd.Flags().GetString("since")
+			until, _ := cmd.Flags().GetString("until")
+			jsonOutput, _ := cmd.Flags().GetBool("json")
+
+			filter := &devkit.JournalFilter{}
+			filter.Package, _ = cmd.Flags().GetString("package")
+			filter.File, _ = cmd.Flags().GetString("file")
+			filter.RunID, _ = cmd.Flags().GetString("run-id")
+
+			if since != "" {
+				filter.Since, err = parseJournalDate(since)
+				if err != nil {
+					fmt.Println(err.Error())
+					os.Exit(1)
+				}
+			}
+			if until != "" {
+				filter.Until, err = parseJournalDate(until)
+				if err != nil {
+					fmt.Println(err.Error())
+					os.Exit(1)
+				}
+				// A date without time includes the whole day.
+				if len(until) == len("2006-01-02") {
+					filter.Until = filter.Until.Add(24*time.Hour - time.Nanosecond)
+				}
+			}
+
+			s := specs.NewAniseRDConfig()
+			if specsFile != "" {
+				s, err = specs.LoadSpecsFile(specsFile)
+				if err != nil {
+					fmt.Println("Error on load specs: " + err.Error())
+					os.Exit(1)
+				}
+			}
+
+			entries, err := devkit.ReadJournal(getJournalFile(cmd, s), filter)
+			if os.IsNotExist(err) {
+				fmt.Println("The journal is empty.")
+				return
+			} else if err != nil {
+				fmt.Println("Error on read journal: " + err.Error())
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				data, _ := json.Marshal(entries)
+				fmt.Println(string(data))
+				return
+			}
+
+			for _, e := range entries {
+				op := e.Operation
+				if e.Status != "" {
+					op += "/" + e.Status
+				}
+				line := fmt.Sprintf("%s %s %s@%s %s %s %s",
+					e.Time.Local().Format(time.RFC3339), e.RunID,
+					e.User, e.Host, e.Command, op, e.File)
+				if e.VersionID != "" {
+					line += " (" + e.VersionID + ")"
+				}
+				if e.Package != "" {
+					line += " [" + e.Package + "]"
+				}
+				if e.Reason != "" {
+					line += " " + e.Reason
+				}
+				if e.Size > 0 {
+					line += " " + units.BytesSize(float64(e.Size))
+				}
+				if e.Error != "" {
+					line += " ERROR: " + e.Error
+				}
+				fmt.Println(line)
+			}
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.String("package", "",
+		"Regex to match the package (category/name-version) of the entries.")
+	flags.String("file", "", "Regex to match the file of the entries.")
+	flags.String("run-id", "", "Show only the entries of the run.")
+	flags.String("since", "",
+		"Show the entries since the date (YYYY-MM-DD or RFC3339).")
+	flags.String("until", "",
+		"Show the entries until the date (YYYY-MM-DD or RFC3339).")
+	flags.Bool("json", false, "Show entries in JSON format.")
+
+	return cmd
+}
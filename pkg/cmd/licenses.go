@@ -0,0 +1,104 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	devkit "github.com/macaroni-os/anise-repo-devkit/pkg/devkit"
+
+	cobra "github.com/spf13/cobra"
+)
+
+func NewLicensesCommand() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "licenses [OPTIONS]",
+		Short: "Show the licenses of the packages of the repository.",
+		Long: `Show the licenses of the artifacts of the repository with the
+packages under each license and the packages without license.
+The command exits with an error if a package has a license not
+admitted by the allowed and denied lists of the specs file.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			jsonOutput, _ := cmd.Flags().GetBool("json")
+			allowed, _ := cmd.Flags().GetStringArray("allow")
+			denied, _ := cmd.Flags().GetStringArray("deny")
+			requireLicense, _ := cmd.Flags().GetBool("require-license")
+
+			setup, err := newRepoSetup(cmd)
+			if err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+
+			conf := setup.Specs.GetLicenses()
+			conf.Allowed = append(conf.Allowed, allowed...)
+			conf.Denied = append(conf.Denied, denied...)
+			if requireLicense {
+				conf.RequireLicense = true
+			}
+			if err = setup.Specs.Validate(); err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+
+			knife, err := devkit.NewRepoKnife(setup.Specs,
+				setup.Backend, setup.Path, setup.Opts)
+			if err != nil {
+				fmt.Println("Error on initialize repo knife: " + err.Error())
+				os.Exit(1)
+			}
+			knife.SetCache(newMetadataCache(cmd, setup))
+
+			inv, err := knife.GetLicensesInventory(cmd.Context())
+			if err != nil {
+				fmt.Println("Error on retrieve licenses: " + err.Error())
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				data, _ := json.Marshal(inv)
+				fmt.Println(string(data))
+			} else {
+				for _, l := range inv.Licenses {
+					fmt.Println(fmt.Sprintf("%s (%d packages):", l.License, len(l.Packages)))
+					for _, p := range l.Packages {
+						fmt.Println("  - " + p)
+					}
+				}
+
+				if len(inv.Unlicensed) > 0 {
+					fmt.Println(fmt.Sprintf("Packages without license (%d):",
+						len(inv.Unlicensed)))
+					for _, p := range inv.Unlicensed {
+						fmt.Println("  - " + p)
+					}
+				}
+
+				for _, v := range inv.Violations {
+					fmt.Println(fmt.Sprintf("[%s] Violation: %s", v.Package, v.Reason))
+				}
+				fmt.Println(fmt.Sprintf("Found %d licenses and %d violations.",
+					len(inv.Licenses), len(inv.Violations)))
+			}
+
+			if len(inv.Violations) > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+
+	var flags = cmd.Flags()
+	addBackendFlags(flags)
+	addCacheFlags(flags)
+	flags.StringArray("allow", []string{}, "License admitted.")
+	flags.StringArray("deny", []string{}, "License not admitted.")
+	flags.Bool("require-license", false,
+		"Report the packages without license as violations.")
+	flags.Bool("json", false, "Show the licenses in JSON format.")
+
+	return cmd
+}
@@ -0,0 +1,74 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	devkit "github.com/macaroni-os/anise-repo-devkit/pkg/devkit"
+
+	cobra "github.com/spf13/cobra"
+)
+
+func NewOwnerCommand() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "owner <path|glob>... [OPTIONS]",
+		Short: "Show the packages that ship the files.",
+		Long: `Search the files shipped by the artifacts of the repository.
+
+A path without slashes matches the base name of the files:
+
+  $ anise-repo-devkit owner /usr/lib/libfoo.so
+  $ anise-repo-devkit owner 'libfoo.so*'
+  $ anise-repo-devkit owner '/usr/bin/*'
+`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			jsonOutput, _ := cmd.Flags().GetBool("json")
+
+			setup, err := newRepoSetup(cmd)
+			if err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+
+			knife, err := devkit.NewRepoKnife(setup.Specs,
+				setup.Backend, setup.Path, setup.Opts)
+			if err != nil {
+				fmt.Println("Error on initialize repo knife: " + err.Error())
+				os.Exit(1)
+			}
+			knife.SetCache(newMetadataCache(cmd, setup))
+
+			owners, err := knife.SearchOwners(cmd.Context(), args)
+			if err != nil {
+				fmt.Println("Error on search owners: " + err.Error())
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				data, _ := json.Marshal(owners)
+				fmt.Println(string(data))
+			} else {
+				for _, o := range owners {
+					fmt.Println(fmt.Sprintf("%s: %s", o.Package, o.File))
+				}
+			}
+
+			if len(owners) == 0 {
+				os.Exit(1)
+			}
+		},
+	}
+
+	var flags = cmd.Flags()
+	addBackendFlags(flags)
+	addCacheFlags(flags)
+	flags.Bool("json", false, "Show the owners in JSON format.")
+
+	return cmd
+}
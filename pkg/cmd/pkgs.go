@@ -8,15 +8,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"regexp"
 	"sort"
 
 	devkit "github.com/macaroni-os/anise-repo-devkit/pkg/devkit"
-	specs "github.com/macaroni-os/anise-repo-devkit/pkg/specs"
 
 	anise_pkg "github.com/geaaru/luet/pkg/package"
 	anise_spectooling "github.com/geaaru/luet/pkg/spectooling"
 	cobra "github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
 )
 
 func NewPkgsCommand() *cobra.Command {
@@ -24,15 +23,9 @@ func NewPkgsCommand() *cobra.Command {
 		Use:   "pkgs [OPTIONS]",
 		Short: "Show packages availables or missings from repository.",
 		PreRun: func(cmd *cobra.Command, args []string) {
-			treePath, _ := cmd.Flags().GetStringArray("tree")
 			listAvailables, _ := cmd.Flags().GetBool("availables")
 			listMissings, _ := cmd.Flags().GetBool("missings")
 
-			if len(treePath) == 0 {
-				fmt.Println("At least one tree path is needed.")
-				os.Exit(1)
-			}
-
 			if (listAvailables && listMissings) ||
 				(!listAvailables && !listMissings) {
 				fmt.Println(
@@ -41,97 +34,72 @@ func NewPkgsCommand() *cobra.Command {
 				os.Exit(1)
 			}
 
+			output, _ := cmd.Flags().GetString("output")
+			switch output {
+			case "text", "json", "table", "yaml", "csv":
+			default:
+				fmt.Println("Invalid output format " + output)
+				os.Exit(1)
+			}
+
+			tmpl, _ := cmd.Flags().GetString("template")
+			if tmpl != "" && (cmd.Flags().Changed("output") || cmd.Flags().Changed("json")) {
+				fmt.Println("The --template option can't be used with --output or --json.")
+				os.Exit(1)
+			}
 		},
 		Run: func(cmd *cobra.Command, args []string) {
-			var s *specs.AniseRDConfig
-			var err error
-
-			specsFile, _ := cmd.Flags().GetString("specs-file")
-			backend, _ := cmd.Flags().GetString("backend")
-			path, _ := cmd.Flags().GetString("path")
-			treePath, _ := cmd.Flags().GetStringArray("tree")
 			listAvailables, _ := cmd.Flags().GetBool("availables")
 			listMissings, _ := cmd.Flags().GetBool("missings")
 			buildOrder, _ := cmd.Flags().GetBool("build-ordered")
 			buildOrderWithResolve, _ := cmd.Flags().GetBool("build-ordered-with-resolve")
 			filters, _ := cmd.Flags().GetStringArray("filter")
-
-			mottainaiProfile, _ := cmd.Flags().GetString("mottainai-profile")
-			mottainaiMaster, _ := cmd.Flags().GetString("mottainai-master")
-			mottainaiApiKey, _ := cmd.Flags().GetString("mottainai-apikey")
-			mottainaiNamespace, _ := cmd.Flags().GetString("mottainai-namespace")
-
-			minioBucket, _ := cmd.Flags().GetString("minio-bucket")
-			minioAccessId, _ := cmd.Flags().GetString("minio-keyid")
-			minioSecret, _ := cmd.Flags().GetString("minio-secret")
-			minioEndpoint, _ := cmd.Flags().GetString("minio-endpoint")
-			minioRegion, _ := cmd.Flags().GetString("minio-region")
+			categories, _ := cmd.Flags().GetStringArray("category")
+			labels, _ := cmd.Flags().GetStringArray("label")
+			annotations, _ := cmd.Flags().GetStringArray("annotation")
+			hidden, _ := cmd.Flags().GetString("hidden")
 
 			jsonOutput, _ := cmd.Flags().GetBool("json")
+			output, _ := cmd.Flags().GetString("output")
+			tmpl, _ := cmd.Flags().GetString("template")
 			limit, _ := cmd.Flags().GetInt32("limit")
 
-			if specsFile == "" {
-				s = specs.NewAniseRDConfig()
-			} else {
-				s, err = specs.LoadSpecsFile(specsFile)
-				if err != nil {
-					fmt.Println("Error on load specs: " + err.Error())
-					os.Exit(1)
-				}
+			if jsonOutput {
+				output = "json"
 			}
 
-			opts := make(map[string]string, 0)
-			if backend == "mottainai" {
-				if mottainaiProfile != "" {
-					opts["mottainai-profile"] = mottainaiProfile
-				}
-				if mottainaiMaster != "" {
-					opts["mottainai-master"] = mottainaiMaster
-				}
-				if mottainaiApiKey != "" {
-					opts["mottainai-apikey"] = mottainaiApiKey
-				}
-				if mottainaiNamespace != "" {
-					opts["mottainai-namespace"] = mottainaiNamespace
-				}
-			} else if backend == "minio" {
-
-				if minioEndpoint != "" {
-					opts["minio-endpoint"] = minioEndpoint
-				} else {
-					opts["minio-endpoint"] = os.Getenv("MINIO_URL")
-				}
-
-				if minioBucket != "" {
-					opts["minio-bucket"] = minioBucket
-				} else {
-					opts["minio-bucket"] = os.Getenv("MINIO_BUCKET")
-				}
-
-				if minioAccessId != "" {
-					opts["minio-keyid"] = minioAccessId
-				} else {
-					opts["minio-keyid"] = os.Getenv("MINIO_ID")
-				}
-
-				if minioSecret != "" {
-					opts["minio-secret"] = minioSecret
-				} else {
-					opts["minio-secret"] = os.Getenv("MINIO_SECRET")
-				}
+			pkgsFilter, err := devkit.NewPkgsFilter(filters, categories,
+				labels, annotations, hidden)
+			if err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
 
-				opts["minio-region"] = minioRegion
+			setup, err := newRepoSetup(cmd)
+			if err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
 
+			if !hasTrees(cmd, setup) {
+				fmt.Println("At least one tree path or the --repo-trees option is needed.")
+				os.Exit(1)
 			}
 
-			repoList, err := devkit.NewRepoList(s, backend, path, opts)
+			repoList, err := devkit.NewRepoList(setup.Specs,
+				setup.Backend, setup.Path, setup.Opts)
 			if err != nil {
 				fmt.Println("Error on initialize repo list: " + err.Error())
 				os.Exit(1)
 			}
 
+			if err = fetchRepositoryTrees(cmd, setup, repoList.RepoKnife); err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+
 			// Loading tree in memory
-			err = repoList.LoadTrees(treePath)
+			err = repoList.LoadTreeSources(setup.Trees)
 			if err != nil {
 				fmt.Println("Erro on loading trees: " + err.Error())
 				os.Exit(1)
@@ -140,7 +108,7 @@ func NewPkgsCommand() *cobra.Command {
 			var list []*anise_pkg.DefaultPackage
 
 			if listAvailables {
-				list, err = repoList.ListPkgsAvailable()
+				list, err = repoList.ListPkgsAvailable(cmd.Context())
 				if err != nil {
 					fmt.Println("Error on retrieve availabile pkgs: " + err.Error())
 					os.Exit(1)
@@ -148,9 +116,9 @@ func NewPkgsCommand() *cobra.Command {
 
 			} else if listMissings {
 				if buildOrder {
-					list, err = repoList.ListPkgsMissingByDeps(treePath, buildOrderWithResolve)
+					list, err = repoList.ListPkgsMissingByDeps(cmd.Context(), setup.GetBuildTreePaths(), buildOrderWithResolve)
 				} else {
-					list, err = repoList.ListPkgsMissing()
+					list, err = repoList.ListPkgsMissing(cmd.Context())
 				}
 
 				if err != nil {
@@ -160,37 +128,14 @@ func NewPkgsCommand() *cobra.Command {
 			}
 
 			// Filter packages
-			if len(filters) > 0 {
-				// Create regex
-				listRegex := []*regexp.Regexp{}
-
-				for _, f := range filters {
-					r := regexp.MustCompile(f)
-					if r != nil {
-						listRegex = append(listRegex, r)
-					} else {
-						fmt.Println("WARNING: Regex " + f + " not compiled.")
-					}
-				}
-
-				if len(listRegex) > 0 {
-					filterList := []*anise_pkg.DefaultPackage{}
-					for _, p := range list {
-						toSkip := true
-						for _, r := range listRegex {
-							if r.MatchString(p.GetPackageName()) {
-								toSkip = false
-								break
-							}
-						}
-						if toSkip {
-							continue
-						}
-						filterList = append(filterList, p)
-					}
-
-					list = filterList
-				}
+			list = pkgsFilter.Filter(list)
+
+			// The limit is applied to the sorted list to return always
+			// the same packages.
+			if !buildOrder {
+				sort.Slice(list, func(i, j int) bool {
+					return list[i].HumanReadableString() < list[j].HumanReadableString()
+				})
 			}
 
 			if limit > 0 {
@@ -206,7 +151,27 @@ func NewPkgsCommand() *cobra.Command {
 				list = newList
 			}
 
-			if jsonOutput {
+			if tmpl != "" || output == "table" || output == "csv" || output == "yaml" {
+				views := repoList.NewPkgsView(list)
+
+				switch {
+				case tmpl != "":
+					err = devkit.WritePkgsTemplate(os.Stdout, views, tmpl)
+				case output == "table":
+					err = devkit.WritePkgsTable(os.Stdout, views)
+				case output == "csv":
+					err = devkit.WritePkgsCSV(os.Stdout, views)
+				default:
+					data, _ := yaml.Marshal(views)
+					fmt.Print(string(data))
+				}
+
+				if err != nil {
+					fmt.Println(err.Error())
+					os.Exit(1)
+				}
+
+			} else if output == "json" {
 
 				listSanitized := []*anise_spectooling.DefaultPackageSanitized{}
 				for _, p := range list {
@@ -216,17 +181,8 @@ func NewPkgsCommand() *cobra.Command {
 				data, _ := json.Marshal(listSanitized)
 				fmt.Println(string(data))
 			} else {
-				orderString := []string{}
 				for _, p := range list {
-					orderString = append(orderString, p.HumanReadableString())
-				}
-
-				if !buildOrder {
-					sort.Strings(orderString)
-				}
-
-				for _, p := range orderString {
-					fmt.Println(p)
+					fmt.Println(p.HumanReadableString())
 				}
 			}
 
@@ -234,21 +190,8 @@ func NewPkgsCommand() *cobra.Command {
 	}
 
 	var flags = cmd.Flags()
-	flags.StringP("backend", "b", "local", "Select backend repository: local|mottainai|minio.")
-	flags.StringP("path", "p", "", "Path of the repository artefacts.")
-	flags.String("mottainai-profile", "", "Set mottainai profile to use.")
-	flags.String("mottainai-master", "", "Set mottainai Server to use.")
-	flags.String("mottainai-apikey", "", "Set mottainai API Key to use.")
-	flags.String("mottainai-namespace", "", "Set mottainai namespace to use.")
-	flags.String("minio-bucket", "",
-		"Set minio bucket to use or set env MINIO_BUCKET.")
-	flags.String("minio-endpoint", "",
-		"Set minio endpoint to use or set env MINIO_URL.")
-	flags.String("minio-keyid", "",
-		"Set minio Access Key to use or set env MINIO_ID.")
-	flags.String("minio-secret", "",
-		"Set minio Access Key to use or set env MINIO_SECRET.")
-	flags.String("minio-region", "", "Optinally define the minio region.")
+	addBackendFlags(flags)
+	addRepoTreesFlag(flags)
 	flags.Bool("availables", false, "Show list of available packages.")
 	flags.Bool("missings", false, "Show list of missing packages.")
 	flags.Bool("build-ordered", false,
@@ -256,9 +199,21 @@ func NewPkgsCommand() *cobra.Command {
 	flags.Bool("build-ordered-with-resolve", false,
 		"Use stage4 tree resolving. Slow. To use with --build-ordered.")
 	flags.Bool("json", false, "Show packages in JSON format.")
-	flags.Int32P("limit", "l", 0, "Limit number of packages returned. 0 means no limit.")
+	flags.StringP("output", "o", "text", "Output format: text|json|table|yaml|csv.")
+	flags.String("template", "",
+		"Go template with the sprig functions to execute for every package.")
+	flags.Int32P("limit", "l", 0,
+		"Limit number of packages returned after the sort by name or the build order. 0 means no limit.")
 	flags.StringArrayP("filter", "f", []string{},
 		"Define one or more regex filter to match packages.")
+	flags.StringArray("category", []string{},
+		"Show only the packages of the category.")
+	flags.StringArray("label", []string{},
+		"Show only the packages with the label key or key=value.")
+	flags.StringArray("annotation", []string{},
+		"Show only the packages with the annotation key or key=value.")
+	flags.String("hidden", devkit.HiddenInclude,
+		"Selection of the hidden packages: include|exclude|only.")
 
 	return cmd
 }
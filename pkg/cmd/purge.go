@@ -0,0 +1,96 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	devkit "github.com/macaroni-os/anise-repo-devkit/pkg/devkit"
+
+	units "github.com/docker/go-units"
+	cobra "github.com/spf13/cobra"
+)
+
+func NewPurgeNoncurrentCommand() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "purge-noncurrent [OPTIONS]",
+		Short: "Purge noncurrent versions of a versioned bucket.",
+		Run: func(cmd *cobra.Command, args []string) {
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			quiet, _ := cmd.Flags().GetBool("quiet")
+			retention, _ := cmd.Flags().GetDuration("retention")
+
+			if err := validateReportFlags(cmd); err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+			setupReportOutput(cmd)
+
+			setup, err := newRepoSetup(cmd)
+			if err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+
+			repoPurger, err := devkit.NewRepoPurger(setup.Specs,
+				setup.Backend, setup.Path, setup.Opts, retention, dryRun)
+			if err != nil {
+				fmt.Println("Error on initialize repo purger: " + err.Error())
+				os.Exit(1)
+			}
+
+			if !quiet {
+				repoPurger.Verbose = true
+			}
+
+			if !dryRun {
+				repoPurger.Journal, err = newJournal(cmd, setup)
+				if err != nil {
+					fmt.Println(err.Error())
+					os.Exit(1)
+				}
+			}
+
+			err = repoPurger.Run(cmd.Context())
+			if jErr := repoPurger.Journal.Close(); jErr != nil {
+				fmt.Println("Error on close journal: " + jErr.Error())
+			}
+			if rErr := writeReport(cmd, repoPurger.GetReport()); rErr != nil {
+				fmt.Println("Error on write report: " + rErr.Error())
+				os.Exit(1)
+			}
+			if err != nil {
+				fmt.Println("Error on purge versions: " + err.Error())
+				os.Exit(1)
+			}
+
+			if dryRun {
+				fmt.Println(fmt.Sprintf(
+					"All done. Purgeable versions %d. Reclaimable storage %s.",
+					len(repoPurger.Versions2Remove),
+					units.BytesSize(float64(repoPurger.ReclaimedSize)),
+				))
+			} else {
+				fmt.Println(fmt.Sprintf(
+					"All done. Purged versions %d. Storage reclaimed %s.",
+					repoPurger.RemovedVersions,
+					units.BytesSize(float64(repoPurger.ReclaimedSize)),
+				))
+			}
+		},
+	}
+
+	var flags = cmd.Flags()
+	addBackendFlags(flags)
+	flags.Duration("retention", 30*24*time.Hour,
+		"Purge the versions noncurrent from more than the retention window.")
+	flags.Bool("dry-run", false, "Only check versions to purge.")
+	flags.Bool("quiet", false, "Quiet output.")
+	addReportFlags(flags)
+
+	return cmd
+}
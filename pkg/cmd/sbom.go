@@ -0,0 +1,109 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	devkit "github.com/macaroni-os/anise-repo-devkit/pkg/devkit"
+
+	cobra "github.com/spf13/cobra"
+)
+
+func NewSBOMCommand() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "sbom [OPTIONS]",
+		Short: "Export the software bill of materials of the repository.",
+		Long: `Export the software bill of materials of the artifacts of the
+repository in the SPDX 2.3 JSON or CycloneDX 1.5 JSON format.
+The document contains the name, the version, the license, the
+upstream URIs, the checksums and the dependencies of every package.`,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			format, _ := cmd.Flags().GetString("format")
+			switch devkit.SBOMFormat(format) {
+			case devkit.SBOMFormatSPDX, devkit.SBOMFormatCycloneDX:
+			default:
+				fmt.Println("Invalid format " + format)
+				os.Exit(1)
+			}
+
+			withFiles, _ := cmd.Flags().GetBool("with-files")
+			if withFiles && devkit.SBOMFormat(format) != devkit.SBOMFormatCycloneDX {
+				fmt.Println("The option --with-files is supported only with the cyclonedx format.")
+				os.Exit(1)
+			}
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			format, _ := cmd.Flags().GetString("format")
+			name, _ := cmd.Flags().GetString("name")
+			output, _ := cmd.Flags().GetString("output-file")
+			withFiles, _ := cmd.Flags().GetBool("with-files")
+
+			setup, err := newRepoSetup(cmd)
+			if err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+
+			if name == "" {
+				name, _ = cmd.Flags().GetString("repo")
+			}
+			if name == "" {
+				name = filepath.Base(setup.Path)
+			}
+
+			knife, err := devkit.NewRepoKnife(setup.Specs,
+				setup.Backend, setup.Path, setup.Opts)
+			if err != nil {
+				fmt.Println("Error on initialize repo knife: " + err.Error())
+				os.Exit(1)
+			}
+			knife.SetCache(newMetadataCache(cmd, setup))
+
+			doc, err := knife.GetSBOM(cmd.Context(), devkit.SBOMOptions{
+				Format:    devkit.SBOMFormat(format),
+				Name:      name,
+				WithFiles: withFiles,
+			})
+			if err != nil {
+				fmt.Println("Error on create the SBOM: " + err.Error())
+				os.Exit(1)
+			}
+
+			data, err := json.MarshalIndent(doc, "", "  ")
+			if err != nil {
+				fmt.Println("Error on encode the SBOM: " + err.Error())
+				os.Exit(1)
+			}
+
+			if output == "-" {
+				fmt.Println(string(data))
+			} else {
+				err = os.WriteFile(output, append(data, '\n'), 0644)
+				if err != nil {
+					fmt.Println("Error on write the SBOM: " + err.Error())
+					os.Exit(1)
+				}
+				fmt.Println(fmt.Sprintf("SBOM written to %s.", output))
+			}
+		},
+	}
+
+	var flags = cmd.Flags()
+	addBackendFlags(flags)
+	addCacheFlags(flags)
+	flags.StringP("format", "f", string(devkit.SBOMFormatSPDX),
+		"Format of the SBOM: spdx|cyclonedx.")
+	flags.String("name", "",
+		"Name of the SBOM document. Default is the repository name.")
+	flags.String("output-file", "-", "File where write the SBOM. Use - for stdout.")
+	flags.Bool("with-files", false,
+		"Include the files of every package. Only with the cyclonedx format.")
+
+	return cmd
+}
@@ -0,0 +1,111 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	devkit "github.com/macaroni-os/anise-repo-devkit/pkg/devkit"
+
+	units "github.com/docker/go-units"
+	cobra "github.com/spf13/cobra"
+)
+
+func printStats(stats *devkit.RepoStats) {
+	fmt.Println(fmt.Sprintf("Packages: %d", stats.Packages))
+	fmt.Println(fmt.Sprintf("Artifacts: %d", stats.Artifacts))
+	fmt.Println(fmt.Sprintf("Packages with multiple versions: %d",
+		stats.MultiVersionPackages))
+	size := "unavailable"
+	if stats.SizeAvailable {
+		size = units.BytesSize(float64(stats.Size))
+	}
+	fmt.Println(fmt.Sprintf("Size: %s", size))
+
+	fmt.Println("\nCategories:")
+	for _, c := range stats.Categories {
+		size = "unavailable"
+		if stats.SizeAvailable {
+			size = units.BytesSize(float64(c.Size))
+		}
+		fmt.Println(fmt.Sprintf("  %-30s %5d packages %5d artifacts %10s",
+			c.Category, c.Packages, c.Artifacts, size))
+	}
+
+	if stats.SizeAvailable {
+		fmt.Println("\nLargest artifacts:")
+		for _, a := range stats.Largest {
+			fmt.Println(fmt.Sprintf("  %-50s %10s", a.Package,
+				units.BytesSize(float64(a.Size))))
+		}
+	}
+
+	fmt.Println("\nCompressions:")
+	compressions := []string{}
+	for c := range stats.Compressions {
+		compressions = append(compressions, c)
+	}
+	sort.Strings(compressions)
+	for _, c := range compressions {
+		fmt.Println(fmt.Sprintf("  %-10s %5d", c, stats.Compressions[c]))
+	}
+
+	fmt.Println("\nBuild timestamps:")
+	for _, p := range stats.BuildPeriods {
+		fmt.Println(fmt.Sprintf("  %-10s %5d", p.Period, p.Artifacts))
+	}
+}
+
+func NewStatsCommand() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "stats [OPTIONS]",
+		Short: "Show the statistics of the repository.",
+		Long: `Show the number of packages and artifacts per category, the
+sizes, the largest artifacts, the compression types and the
+distribution of the build timestamps by month.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			jsonOutput, _ := cmd.Flags().GetBool("json")
+			top, _ := cmd.Flags().GetInt("top")
+
+			setup, err := newRepoSetup(cmd)
+			if err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+
+			knife, err := devkit.NewRepoKnife(setup.Specs,
+				setup.Backend, setup.Path, setup.Opts)
+			if err != nil {
+				fmt.Println("Error on initialize repo knife: " + err.Error())
+				os.Exit(1)
+			}
+			knife.SetCache(newMetadataCache(cmd, setup))
+
+			stats, err := knife.GetStats(cmd.Context(), top)
+			if err != nil {
+				fmt.Println("Error on retrieve statistics: " + err.Error())
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				data, _ := json.Marshal(stats)
+				fmt.Println(string(data))
+			} else {
+				printStats(stats)
+			}
+		},
+	}
+
+	var flags = cmd.Flags()
+	addBackendFlags(flags)
+	addCacheFlags(flags)
+	flags.Int("top", 10, "Number of the largest artifacts to show.")
+	flags.Bool("json", false, "Show the statistics in JSON format.")
+
+	return cmd
+}
@@ -0,0 +1,127 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	specs "github.com/macaroni-os/anise-repo-devkit/pkg/specs"
+
+	. "github.com/geaaru/luet/pkg/logger"
+	artifact "github.com/geaaru/luet/pkg/v2/compiler/types/artifact"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// MetadataCache stores the metadata files downloaded from a backend.
+// An entry is valid until the size or the modification time of the
+// metadata file changes, so the cache is usable only with the backends
+// that implement specs.RepoBackendFilesInfo.
+type MetadataCache struct {
+	Dir string
+}
+
+// NewMetadataCache returns the cache of the repository identified by
+// the namespace, for example the backend and the path of the repository.
+func NewMetadataCache(dir, namespace string) *MetadataCache {
+	return &MetadataCache{
+		Dir: filepath.Join(dir, fmt.Sprintf("%x", sha256.Sum256([]byte(namespace)))[:16]),
+	}
+}
+
+// DefaultCacheDir returns $XDG_CACHE_HOME/anise-repo-devkit.
+func DefaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "anise-repo-devkit")
+}
+
+// IsCacheable returns true if the file has the information
+// needed to validate the cache entry.
+func (m *MetadataCache) IsCacheable(f specs.RepoFile) bool {
+	return f.Size > 0 && !f.LastModified.IsZero()
+}
+
+func (m *MetadataCache) entryFile(f specs.RepoFile) string {
+	key := fmt.Sprintf("%s|%d|%d", f.Name, f.Size, f.LastModified.UnixNano())
+	return filepath.Join(m.Dir, fmt.Sprintf("%x.yaml", sha256.Sum256([]byte(key))))
+}
+
+// Get returns the artifact of the file if it's available in the cache.
+func (m *MetadataCache) Get(f specs.RepoFile) (*artifact.PackageArtifact, bool) {
+	if !m.IsCacheable(f) {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(m.entryFile(f))
+	if err != nil {
+		return nil, false
+	}
+
+	art, err := artifact.NewPackageArtifactFromYaml(data)
+	if err != nil {
+		DebugC(fmt.Sprintf("[%s] Invalid cache entry: %s", f.Name, err.Error()))
+		return nil, false
+	}
+
+	return art, true
+}
+
+// Put stores the artifact of the file in the cache.
+func (m *MetadataCache) Put(f specs.RepoFile, art *artifact.PackageArtifact) error {
+	if !m.IsCacheable(f) {
+		return nil
+	}
+
+	data, err := yaml.Marshal(art)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(m.Dir, 0755); err != nil {
+		return err
+	}
+
+	// Write a temporary file to avoid partial entries.
+	entry := m.entryFile(f)
+	tmp := entry + ".tmp"
+	if err = os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, entry)
+}
+
+// Prune removes the entries of the files not available anymore.
+func (m *MetadataCache) Prune(files []specs.RepoFile) error {
+	valid := make(map[string]bool, len(files))
+	for _, f := range files {
+		valid[filepath.Base(m.entryFile(f))] = true
+	}
+
+	entries, err := os.ReadDir(m.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".yaml") || valid[e.Name()] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(m.Dir, e.Name())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
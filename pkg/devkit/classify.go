@@ -0,0 +1,94 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"path/filepath"
+	"strings"
+
+	tmtools "github.com/geaaru/time-master/pkg/tools"
+)
+
+// FileClass identifies the type of a file of the repository.
+type FileClass string
+
+const (
+	FileClassIndex     FileClass = "index"
+	FileClassMetadata  FileClass = "metadata"
+	FileClassTarball   FileClass = "tarball"
+	FileClassSignature FileClass = "signature"
+	FileClassChecksum  FileClass = "checksum"
+	FileClassUnknown   FileClass = "unknown"
+)
+
+var (
+	// Repository files
+	repoRegex = []string{
+		"repository.meta.yaml.tar.*|repository.meta.yaml",
+		"repository.yaml",
+		"tree.tar.*|tree.tar",
+		"compilertree.tar.*|compilertree.tar",
+	}
+
+	metaFilesRegex = []string{
+		".*metadata.yaml$",
+	}
+
+	pkgFilesRegex = []string{
+		".*package.tar$|.*package.tar.*",
+	}
+
+	signatureFilesRegex = []string{
+		"[.](asc|sig|gpg|minisig)$",
+	}
+
+	checksumFilesRegex = []string{
+		"[.](sha1|sha256|sha512|md5|sha256sum|sha512sum|md5sum|b3)$",
+		"(?i)^(sha1|sha256|sha512|md5|b3)sums(\\..*)?$",
+	}
+)
+
+// ClassifyFile returns the class of a file of the repository based
+// on the file name. The sidecar files (signatures and checksums) are
+// identified before the files they refer to.
+func ClassifyFile(f string) FileClass {
+	switch {
+	case tmtools.RegexEntry(f, signatureFilesRegex):
+		return FileClassSignature
+	case tmtools.RegexEntry(f, checksumFilesRegex):
+		return FileClassChecksum
+	case tmtools.RegexEntry(f, repoRegex):
+		return FileClassIndex
+	case tmtools.RegexEntry(f, metaFilesRegex):
+		return FileClassMetadata
+	case tmtools.RegexEntry(f, pkgFilesRegex):
+		return FileClassTarball
+	default:
+		return FileClassUnknown
+	}
+}
+
+// SidecarParent returns the file a signature or checksum file refers
+// to, resolving the sidecars of others sidecars (for example the
+// signature of a checksum file). It returns an empty string for the
+// files that aren't sidecars and for the aggregated checksum files
+// (SHA256SUMS, etc.) that don't refer to a single file.
+func SidecarParent(f string) string {
+	class := ClassifyFile(f)
+	if class != FileClassSignature && class != FileClassChecksum {
+		return ""
+	}
+
+	for class == FileClassSignature || class == FileClassChecksum {
+		parent := strings.TrimSuffix(f, filepath.Ext(f))
+		if parent == f || parent == "" {
+			return ""
+		}
+		f = parent
+		class = ClassifyFile(f)
+	}
+
+	return f
+}
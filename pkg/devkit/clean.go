@@ -5,16 +5,34 @@ See AUTHORS and LICENSE for the license details and contributors.
 package devkit
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sort"
 
 	specs "github.com/macaroni-os/anise-repo-devkit/pkg/specs"
 
+	units "github.com/docker/go-units"
 	. "github.com/geaaru/luet/pkg/logger"
 )
 
+// Max number of files removed with a single batch request.
+const CleanBatchSize = 1000
+
 type RepoCleaner struct {
 	*RepoKnife
-	DryRun bool
+	DryRun       bool
+	RemovedFiles int
+	// Storage reclaimed in bytes reported by the backend.
+	ReclaimedSize int64
+	// The result of every file processed.
+	Results map[string]specs.CleanResult
+
+	// Skip the safety guards.
+	Force bool
+	// Optional function called to confirm the removal when the safety
+	// guards are exceeded. If nil the cleaner aborts.
+	Confirm func(string) bool
 }
 
 func NewRepoCleaner(s *specs.AniseRDConfig,
@@ -34,25 +52,224 @@ func NewRepoCleaner(s *specs.AniseRDConfig,
 	return ans, nil
 }
 
-func (c *RepoCleaner) Run() error {
+func NewRepoCleanerWithBackend(s *specs.AniseRDConfig,
+	handler specs.RepoBackendHandler, dryRun bool) *RepoCleaner {
+	return &RepoCleaner{
+		RepoKnife: NewRepoKnifeWithBackend(s, handler),
+		DryRun:    dryRun,
+	}
+}
+
+func (c *RepoCleaner) Run(ctx context.Context) error {
+
+	err := c.RepoKnife.Analyze(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.RemovedFiles = 0
+	c.ReclaimedSize = 0
+	c.Results = make(map[string]specs.CleanResult, 0)
 
-	err := c.RepoKnife.Analyze()
+	if len(c.Files2Remove) == 0 {
+		InfoC("No files to remove.")
+		return nil
+	}
 
-	if len(c.Files2Remove) > 0 {
+	if c.DryRun {
 		for _, f := range c.Files2Remove {
-			if c.DryRun {
-				InfoC(fmt.Sprintf("[%s] Could be removed.", f))
-			} else {
-				err = c.BackendHandler.CleanFile(f)
-				if err != nil {
-					Error(fmt.Sprintf("[%s] Error on removing file: %s", f, err.Error()))
-				} else {
-					InfoC(fmt.Sprintf("[%s] Removed.", f))
-				}
+			InfoC(fmt.Sprintf("[%s] Could be removed.", f))
+		}
+		c.printSummary()
+		if err := c.checkSafetyGuards(); err != nil {
+			Warning(err.Error())
+		}
+		return nil
+	}
+
+	c.printSummary()
+	if err := c.checkSafetyGuards(); err != nil {
+		if c.Force {
+			Warning(err.Error() + " Forced.")
+		} else if c.Confirm == nil || !c.Confirm(err.Error()+" Continue?") {
+			return errors.New(err.Error() + " Use --force to skip the check.")
+		}
+	}
+
+	batchCleaner, isBatch := c.BackendHandler.(specs.RepoBackendBatchCleaner)
+
+	for i := 0; i < len(c.Files2Remove); {
+		// Stop before the next file or batch on interrupt. The removal
+		// in progress is never interrupted.
+		if ctx.Err() != nil {
+			return fmt.Errorf(
+				"Clean interrupted after %d of %d files: %w",
+				c.RemovedFiles, len(c.Files2Remove), ctx.Err())
+		}
+
+		if isBatch {
+			end := i + CleanBatchSize
+			if end > len(c.Files2Remove) {
+				end = len(c.Files2Remove)
+			}
+
+			if err := c.recordCleanIntents(c.Files2Remove[i:end]); err != nil {
+				return err
+			}
+
+			results := batchCleaner.CleanFiles(context.WithoutCancel(ctx),
+				c.Files2Remove[i:end])
+			for _, r := range results {
+				c.logCleanResult(r)
+			}
+			i = end
+		} else {
+			f := c.Files2Remove[i]
+			if err := c.recordCleanIntents([]string{f}); err != nil {
+				return err
 			}
+
+			c.logCleanResult(specs.CleanResult{
+				File:  f,
+				Error: c.BackendHandler.CleanFile(context.WithoutCancel(ctx), f),
+			})
+			i++
 		}
+	}
+
+	return nil
+}
+
+// newCleanEntry returns the journal entry of the removal of a file.
+func (c *RepoCleaner) newCleanEntry(f string) *JournalEntry {
+	return &JournalEntry{
+		Operation: JournalOpCleanFile,
+		File:      f,
+		Package:   c.GetFilePackage(f),
+		Reason:    string(c.Removals[f]),
+		Size:      c.FilesSize[f],
+	}
+}
+
+// recordCleanIntents records in the journal the files that are going
+// to be removed before the request to the backend.
+func (c *RepoCleaner) recordCleanIntents(files []string) error {
+	entries := []*JournalEntry{}
+	for _, f := range files {
+		entries = append(entries, c.newCleanEntry(f))
+	}
+	return c.recordJournalIntents(entries)
+}
+
+// isVersionedBackend returns true if the backend keeps the versions
+// of the removed files.
+func (c *RepoCleaner) isVersionedBackend() bool {
+	vBackend, ok := c.BackendHandler.(specs.RepoBackendVersioned)
+	return ok && vBackend.IsVersioned()
+}
+
+func (c *RepoCleaner) logCleanResult(r specs.CleanResult) {
+	// Use the size of the file when the backend doesn't return it. The
+	// removal of a file of a versioned bucket could only add a delete
+	// marker without reclaiming storage.
+	if r.Error == nil && r.Reclaimed == 0 && !c.isVersionedBackend() {
+		r.Reclaimed = c.FilesSize[r.File]
+	}
+	c.Results[r.File] = r
+
+	entry := c.newCleanEntry(r.File)
+	entry.Status = JournalStatusDone
+	entry.Size = r.Reclaimed
+	if r.Error != nil {
+		entry.Status = JournalStatusFailed
+		entry.Error = r.Error.Error()
+	}
+	c.recordJournal(entry)
+
+	if r.Error != nil {
+		Error(fmt.Sprintf("[%s] Error on removing file: %s", r.File, r.Error.Error()))
 	} else {
-		InfoC("No files to remove.")
+		if r.Versions > 1 {
+			InfoC(fmt.Sprintf("[%s] Removed (%d versions, %s).", r.File,
+				r.Versions, units.BytesSize(float64(r.Reclaimed))))
+		} else {
+			InfoC(fmt.Sprintf("[%s] Removed.", r.File))
+		}
+		c.RemovedFiles++
+		c.ReclaimedSize += r.Reclaimed
+	}
+}
+
+// GetReport returns the report of the last run.
+func (c *RepoCleaner) GetReport() *Report {
+	ans := NewReport("clean", c.DryRun)
+	ans.ProcessedFiles = c.ProcessedFiles
+	ans.Trees = c.Trees
+	ans.BuildTrees = c.BuildTrees
+
+	for _, f := range c.Files2Remove {
+		rf := ReportFile{
+			File:    f,
+			Package: c.GetFilePackage(f),
+			Reason:  string(c.Removals[f]),
+			Size:    c.FilesSize[f],
+			Status:  ReportStatusRemovable,
+		}
+
+		if !c.DryRun {
+			r, ok := c.Results[f]
+			switch {
+			case !ok:
+				rf.Status = ReportStatusSkipped
+			case r.Error != nil:
+				rf.Status = ReportStatusFailed
+				rf.Error = r.Error.Error()
+			default:
+				rf.Status = ReportStatusRemoved
+				rf.Reclaimed = r.Reclaimed
+			}
+		}
+
+		ans.AddFile(rf)
+	}
+
+	return ans
+}
+
+func (c *RepoCleaner) printSummary() {
+	byReason := c.GetRemovalsByReason()
+	reasons := []string{}
+	for r := range byReason {
+		reasons = append(reasons, string(r))
+	}
+	sort.Strings(reasons)
+
+	InfoC(fmt.Sprintf("Files to remove %d of %d:",
+		len(c.Files2Remove), c.ProcessedFiles))
+	for _, r := range reasons {
+		InfoC(fmt.Sprintf("  - %s: %d", r, byReason[RemoveReason(r)]))
+	}
+}
+
+// checkSafetyGuards returns an error if the files to remove exceed
+// the limits defined in the cleaner specs.
+func (c *RepoCleaner) checkSafetyGuards() error {
+	cleaner := c.Specs.GetCleaner()
+	n := len(c.Files2Remove)
+
+	if cleaner.GetMaxDeleteFiles() > 0 && n > cleaner.GetMaxDeleteFiles() {
+		return errors.New(fmt.Sprintf(
+			"The files to remove (%d) exceed the max number of files (%d).",
+			n, cleaner.GetMaxDeleteFiles()))
+	}
+
+	if c.ProcessedFiles > 0 {
+		perc := float64(n) * 100 / float64(c.ProcessedFiles)
+		if perc > cleaner.GetMaxDeletePercent() {
+			return errors.New(fmt.Sprintf(
+				"The files to remove (%.1f%%) exceed the max percentage of files (%.1f%%).",
+				perc, cleaner.GetMaxDeletePercent()))
+		}
 	}
 
 	return nil
@@ -0,0 +1,260 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/macaroni-os/anise-repo-devkit/pkg/backends"
+	specs "github.com/macaroni-os/anise-repo-devkit/pkg/specs"
+)
+
+func newFixtureCleaner(t *testing.T, fixtures []*fixture, dryRun bool,
+	extraFiles ...string) (*RepoCleaner, *backends.BackendMemory) {
+	t.Helper()
+
+	s := specs.NewAniseRDConfig()
+	b := newFixtureBackend(t, s, fixtures, extraFiles...)
+	cleaner := NewRepoCleanerWithBackend(s, b, dryRun)
+	if err := cleaner.LoadTrees([]string{newFixtureTree(t, fixtures)}); err != nil {
+		t.Fatal(err)
+	}
+
+	return cleaner, b
+}
+
+var cleanerFixtures = []*fixture{
+	{Category: "app", Name: "foo", Version: "1.0", Compression: "zst",
+		InTree: false, WithMeta: true, WithTarball: true},
+	{Category: "app", Name: "foo", Version: "1.1", Compression: "zst",
+		InTree: true, WithMeta: true, WithTarball: true},
+	{Category: "app", Name: "bar", Version: "1.0", Compression: "zst",
+		InTree: true, WithMeta: false, WithTarball: true},
+}
+
+func TestCleanerDryRun(t *testing.T) {
+	cleaner, b := newFixtureCleaner(t, cleanerFixtures, true)
+
+	if err := cleaner.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls := b.GetCalls("CleanFile"); len(calls) != 0 {
+		t.Fatalf("Expected no CleanFile calls on dry-run, got %v", calls)
+	}
+
+	assertFiles(t, cleaner.Files2Remove,
+		cleanerFixtures[0].MetaFile(), cleanerFixtures[0].Tarball(),
+		cleanerFixtures[2].Tarball(),
+	)
+	if cleaner.RemovedFiles != 0 {
+		t.Fatalf("Expected 0 removed files, got %d", cleaner.RemovedFiles)
+	}
+}
+
+func TestCleanerRun(t *testing.T) {
+	cleaner, b := newFixtureCleaner(t, cleanerFixtures, false, "README.txt")
+	cleaner.Specs.Cleaner.FilesPolicy.Unknown = specs.FilePolicyDelete
+	cleaner.Force = true
+
+	if err := cleaner.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	removed := []string{}
+	for _, c := range b.GetCalls("CleanFile") {
+		removed = append(removed, c.File)
+	}
+
+	assertFiles(t, removed,
+		cleanerFixtures[0].MetaFile(), cleanerFixtures[0].Tarball(),
+		cleanerFixtures[2].Tarball(), "README.txt",
+	)
+	if cleaner.RemovedFiles != 4 {
+		t.Fatalf("Expected 4 removed files, got %d", cleaner.RemovedFiles)
+	}
+
+	for _, f := range []string{
+		cleanerFixtures[1].MetaFile(), cleanerFixtures[1].Tarball(),
+	} {
+		if !b.HasFile(f) {
+			t.Fatalf("File %s removed", f)
+		}
+	}
+}
+
+func TestCleanerRemoveError(t *testing.T) {
+	cleaner, b := newFixtureCleaner(t, cleanerFixtures, false)
+	cleaner.Force = true
+	b.Errors[cleanerFixtures[2].Tarball()] = errors.New("permission denied")
+
+	if err := cleaner.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if cleaner.RemovedFiles != 2 {
+		t.Fatalf("Expected 2 removed files, got %d", cleaner.RemovedFiles)
+	}
+}
+
+func TestCleanerBatch(t *testing.T) {
+	cleaner, b := newFixtureCleaner(t, cleanerFixtures, false)
+	cleaner.Force = true
+	b.Errors[cleanerFixtures[0].Tarball()] = errors.New("permission denied")
+
+	if err := cleaner.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls := b.GetCalls("CleanFiles"); len(calls) != 1 {
+		t.Fatalf("Expected 1 CleanFiles call, got %v", calls)
+	}
+	if cleaner.RemovedFiles != 2 || len(cleaner.Results) != 3 {
+		t.Fatalf("Expected 2 removed files of 3, got %d (%v)",
+			cleaner.RemovedFiles, cleaner.Results)
+	}
+	for f, r := range cleaner.Results {
+		if r.File != f {
+			t.Fatalf("Result of %s stored for %s", r.File, f)
+		}
+		if (r.Error != nil) != (f == cleanerFixtures[0].Tarball()) {
+			t.Fatalf("Unexpected result of %s: %v", f, r.Error)
+		}
+	}
+}
+
+func TestCleanerAllVersions(t *testing.T) {
+	cleaner, b := newFixtureCleaner(t, cleanerFixtures, false)
+	cleaner.Force = true
+	b.Versioned = true
+	b.RemoveAllVersions = true
+	b.AddFile(cleanerFixtures[0].Tarball(), make([]byte, 100))
+
+	if err := cleaner.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	r := cleaner.Results[cleanerFixtures[0].Tarball()]
+	if r.Error != nil || r.Versions != 2 || r.Reclaimed != 100 {
+		t.Fatalf("Unexpected result %+v", r)
+	}
+	if v := b.GetVersions(cleanerFixtures[0].Tarball()); len(v) != 0 {
+		t.Fatalf("Expected all versions removed, got %+v", v)
+	}
+}
+
+func TestCleanerWithoutBatch(t *testing.T) {
+	s := specs.NewAniseRDConfig()
+	b := newFixtureBackend(t, s, cleanerFixtures)
+	// Hide the CleanFiles method of the memory backend.
+	handler := struct {
+		specs.RepoBackendHandler
+		specs.RepoBackendFilesInfo
+	}{b, b}
+	cleaner := NewRepoCleanerWithBackend(s, handler, false)
+	if err := cleaner.LoadTrees([]string{newFixtureTree(t, cleanerFixtures)}); err != nil {
+		t.Fatal(err)
+	}
+	cleaner.Force = true
+	b.Errors[cleanerFixtures[2].Tarball()] = errors.New("permission denied")
+
+	if err := cleaner.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls := b.GetCalls("CleanFiles"); len(calls) != 0 {
+		t.Fatalf("Expected no CleanFiles calls, got %v", calls)
+	}
+	if calls := b.GetCalls("CleanFile"); len(calls) != 3 {
+		t.Fatalf("Expected 3 CleanFile calls, got %v", calls)
+	}
+	if cleaner.RemovedFiles != 2 || cleaner.Results[cleanerFixtures[2].Tarball()].Error == nil {
+		t.Fatalf("Unexpected results %v", cleaner.Results)
+	}
+}
+
+func TestCleanerAnalyzeError(t *testing.T) {
+	cleaner, b := newFixtureCleaner(t, cleanerFixtures, false)
+	b.Errors[cleanerFixtures[1].MetaFile()] = errors.New("connection reset")
+
+	if err := cleaner.Run(context.Background()); err == nil {
+		t.Fatal("Expected error on analyze")
+	}
+
+	if calls := b.GetCalls("CleanFile"); len(calls) != 0 {
+		t.Fatalf("Expected no CleanFile calls after analyze error, got %v", calls)
+	}
+}
+
+func TestCleanerInterrupted(t *testing.T) {
+	cleaner, b := newFixtureCleaner(t, cleanerFixtures, false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := cleaner.Run(ctx); err == nil {
+		t.Fatal("Expected error on cancelled context")
+	}
+
+	if calls := b.GetCalls("CleanFile"); len(calls) != 0 {
+		t.Fatalf("Expected no CleanFile calls, got %v", calls)
+	}
+}
+
+func TestCleanerSafetyGuards(t *testing.T) {
+	// 3 files of 5 to remove
+	for _, tc := range []struct {
+		name     string
+		percent  float64
+		maxFiles int
+		force    bool
+		confirm  func(string) bool
+		removed  int
+		fail     bool
+	}{
+		{name: "default percentage", removed: 0, fail: true},
+		{name: "percentage", percent: 60, removed: 3},
+		{name: "max files", percent: 100, maxFiles: 2, removed: 0, fail: true},
+		{name: "force", maxFiles: 2, force: true, removed: 3},
+		{name: "confirm yes", confirm: func(string) bool { return true }, removed: 3},
+		{name: "confirm no", confirm: func(string) bool { return false }, fail: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cleaner, b := newFixtureCleaner(t, cleanerFixtures, false)
+			cleaner.Specs.Cleaner.MaxDeletePercent = tc.percent
+			cleaner.Specs.Cleaner.MaxDeleteFiles = tc.maxFiles
+			cleaner.Force = tc.force
+			cleaner.Confirm = tc.confirm
+
+			err := cleaner.Run(context.Background())
+			if tc.fail && err == nil {
+				t.Fatal("Expected error")
+			} else if !tc.fail && err != nil {
+				t.Fatal(err)
+			}
+
+			if calls := b.GetCalls("CleanFile"); len(calls) != tc.removed {
+				t.Fatalf("Expected %d CleanFile calls, got %d", tc.removed, len(calls))
+			}
+		})
+	}
+}
+
+func TestRemovalsByReason(t *testing.T) {
+	cleaner, _ := newFixtureCleaner(t, cleanerFixtures, true, "README.txt")
+	cleaner.Specs.Cleaner.FilesPolicy.Unknown = specs.FilePolicyDelete
+	if err := cleaner.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	byReason := cleaner.GetRemovalsByReason()
+	if byReason[ReasonDroppedFromTree] != 2 ||
+		byReason[ReasonOrphanTarball] != 1 ||
+		byReason[ReasonUnknownFile] != 1 {
+		t.Fatalf("Unexpected removals %v", byReason)
+	}
+}
@@ -0,0 +1,137 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	anise_pkg "github.com/geaaru/luet/pkg/package"
+)
+
+// FileCollision describes a file shipped by two packages
+// that don't conflict.
+type FileCollision struct {
+	File     string `json:"file" yaml:"file"`
+	Package1 string `json:"package1" yaml:"package1"`
+	Package2 string `json:"package2" yaml:"package2"`
+}
+
+// isDeclaredConflict returns true if one of the packages declares
+// a conflict with the other one.
+func isDeclaredConflict(a, b *anise_pkg.DefaultPackage) bool {
+	for _, c := range a.GetConflicts() {
+		if packageSatisfies(c, b) {
+			return true
+		}
+	}
+	for _, c := range b.GetConflicts() {
+		if packageSatisfies(c, a) {
+			return true
+		}
+	}
+	return false
+}
+
+// getLatestArtifacts returns the metafiles of the latest version
+// of every package.
+func (c *RepoKnife) getLatestArtifacts() []string {
+	mLatest := make(map[string]string, 0)
+	for m, art := range c.MetaMap {
+		p := artifactPackage(art)
+		if p == nil {
+			continue
+		}
+
+		key := p.GetPackageName()
+		if cur, ok := mLatest[key]; ok {
+			cp := artifactPackage(c.MetaMap[cur])
+			if !isNewerVersion(p.GetVersion(), cp.GetVersion()) &&
+				(p.GetVersion() != cp.GetVersion() || m > cur) {
+				continue
+			}
+		}
+		mLatest[key] = m
+	}
+
+	ans := []string{}
+	for _, m := range mLatest {
+		ans = append(ans, m)
+	}
+	sort.Strings(ans)
+	return ans
+}
+
+// SearchCollisions returns the files shipped by multiple packages. The
+// versions of the same package, the packages that declare a conflict
+// between them and the paths and packages allowed by the specs are
+// ignored. If allVersions is false only the latest version of every
+// package is checked.
+func (c *RepoKnife) SearchCollisions(ctx context.Context, allVersions bool) ([]FileCollision, error) {
+	ans := []FileCollision{}
+	conf := c.Specs.GetCollisions()
+
+	err := c.LoadMetadata(ctx, nil)
+	if err != nil {
+		return ans, err
+	}
+
+	metas := []string{}
+	if allVersions {
+		for m := range c.MetaMap {
+			metas = append(metas, m)
+		}
+		sort.Strings(metas)
+	} else {
+		metas = c.getLatestArtifacts()
+	}
+
+	// Build the index path -> packages
+	index := make(map[string][]*anise_pkg.DefaultPackage, 0)
+	for _, m := range metas {
+		art := c.MetaMap[m]
+		p := artifactPackage(art)
+		if p == nil || conf.IsExcludedPkg(p) {
+			continue
+		}
+
+		for _, f := range art.Files {
+			f = "/" + strings.TrimPrefix(f, "/")
+			if conf.IsAllowedPath(f) {
+				continue
+			}
+			index[f] = append(index[f], p)
+		}
+	}
+
+	files := []string{}
+	for f, pkgs := range index {
+		if len(pkgs) > 1 {
+			files = append(files, f)
+		}
+	}
+	sort.Strings(files)
+
+	for _, f := range files {
+		pkgs := index[f]
+		for i := 0; i < len(pkgs); i++ {
+			for j := i + 1; j < len(pkgs); j++ {
+				if pkgs[i].GetPackageName() == pkgs[j].GetPackageName() ||
+					isDeclaredConflict(pkgs[i], pkgs[j]) {
+					continue
+				}
+
+				ans = append(ans, FileCollision{
+					File:     f,
+					Package1: pkgs[i].HumanReadableString(),
+					Package2: pkgs[j].HumanReadableString(),
+				})
+			}
+		}
+	}
+
+	return ans, nil
+}
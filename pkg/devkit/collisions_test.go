@@ -0,0 +1,67 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"context"
+	"testing"
+
+	specs "github.com/macaroni-os/anise-repo-devkit/pkg/specs"
+
+	anise_pkg "github.com/geaaru/luet/pkg/package"
+)
+
+var collisionFixtures = []*fixture{
+	{Category: "app", Name: "foo", Version: "1.0", Compression: "zst",
+		Files:  []string{"usr/bin/tool", "usr/bin/foo-old"},
+		InTree: true, WithMeta: true, WithTarball: true},
+	{Category: "app", Name: "foo", Version: "1.1", Compression: "zst",
+		Files:  []string{"usr/bin/tool", "usr/bin/foo", "usr/share/info/dir"},
+		InTree: true, WithMeta: true, WithTarball: true},
+	{Category: "app", Name: "bar", Version: "1.0", Compression: "zst",
+		Files:  []string{"usr/bin/tool", "usr/bin/foo-old", "usr/share/info/dir"},
+		InTree: true, WithMeta: true, WithTarball: true},
+	{Category: "app", Name: "baz", Version: "1.0", Compression: "zst",
+		Files: []string{"usr/bin/tool"},
+		Conflicts: []*anise_pkg.DefaultPackage{
+			{Category: "app", Name: "foo", Version: ">=1.0"},
+		},
+		InTree: true, WithMeta: true, WithTarball: true},
+}
+
+func collisionsStrings(collisions []FileCollision) []string {
+	ans := []string{}
+	for _, c := range collisions {
+		ans = append(ans, c.File+" "+c.Package1+" "+c.Package2)
+	}
+	return ans
+}
+
+func TestSearchCollisions(t *testing.T) {
+	s := specs.NewAniseRDConfig()
+	knife, _ := newFixtureKnife(t, s, collisionFixtures)
+
+	collisions, err := knife.SearchCollisions(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFiles(t, collisionsStrings(collisions),
+		"/usr/bin/tool app/bar-1.0 app/foo-1.1",
+		"/usr/bin/tool app/bar-1.0 app/baz-1.0",
+		"/usr/share/info/dir app/bar-1.0 app/foo-1.1",
+	)
+
+	s.Collisions.AllowedPaths = []string{"^/usr/share/info/dir$"}
+	s.Collisions.ExcludePkgs = []specs.AnisePackage{{Category: "app", Name: "baz"}}
+	collisions, err = knife.SearchCollisions(context.Background(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFiles(t, collisionsStrings(collisions),
+		"/usr/bin/foo-old app/bar-1.0 app/foo-1.0",
+		"/usr/bin/tool app/bar-1.0 app/foo-1.0",
+		"/usr/bin/tool app/bar-1.0 app/foo-1.1",
+	)
+}
@@ -0,0 +1,172 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	specs "github.com/macaroni-os/anise-repo-devkit/pkg/specs"
+
+	. "github.com/geaaru/luet/pkg/logger"
+	artifact "github.com/geaaru/luet/pkg/v2/compiler/types/artifact"
+)
+
+const ReasonDuplicate RemoveReason = "duplicate"
+
+// parseBuildTimestamp parses the build timestamp of a package. The
+// timestamp could be a unix time or the string of a time.Time.
+func parseBuildTimestamp(s string) time.Time {
+	if s == "" {
+		return time.Time{}
+	}
+
+	if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(sec, 0)
+	}
+
+	// Drop the monotonic clock reading of time.Time.String()
+	if idx := strings.Index(s, " m="); idx > 0 {
+		s = s[:idx]
+	}
+
+	for _, layout := range []string{
+		"2006-01-02 15:04:05.999999999 -0700 MST",
+		time.RFC3339Nano,
+	} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+func artifactBuildTime(art *artifact.PackageArtifact) time.Time {
+	if art.CompileSpec != nil && art.CompileSpec.Package != nil &&
+		art.CompileSpec.Package.GetBuildTimestamp() != "" {
+		return parseBuildTimestamp(art.CompileSpec.Package.GetBuildTimestamp())
+	}
+	if art.Runtime != nil {
+		return parseBuildTimestamp(art.Runtime.GetBuildTimestamp())
+	}
+	return time.Time{}
+}
+
+// isPreferredArtifact returns true if the artifact of the metafile a is
+// preferred to the artifact of the metafile b. The compression preference
+// is checked before the build timestamp.
+func (c *RepoKnife) isPreferredArtifact(a, b string) bool {
+	artA, artB := c.MetaMap[a], c.MetaMap[b]
+
+	pref := c.Specs.GetCleaner().CompressionPreference
+	rank := func(art *artifact.PackageArtifact) int {
+		for idx, p := range pref {
+			if p == string(art.CompressionType) {
+				return idx
+			}
+		}
+		return len(pref)
+	}
+
+	if rA, rB := rank(artA), rank(artB); rA != rB {
+		return rA < rB
+	}
+
+	if tA, tB := artifactBuildTime(artA), artifactBuildTime(artB); !tA.Equal(tB) {
+		return tA.After(tB)
+	}
+
+	return a < b
+}
+
+// CheckDuplicates searches the artifacts of the same package
+// (category/name/version) and the tarballs not referenced by any
+// metadata. The preferred artifact is kept and the others are
+// reported or removed based on the cleaner duplicates_policy.
+func (c *RepoKnife) CheckDuplicates() {
+	// The key is the duplicate file and the value the metafile kept.
+	c.Duplicates = make(map[string]string, 0)
+
+	mPkgs := make(map[string][]string, 0)
+	referenced := make(map[string]string, 0)
+	for m, art := range c.MetaMap {
+		if _, ok := c.Removals[m]; ok {
+			continue
+		}
+		referenced[filepath.Base(art.Path)] = m
+
+		if art.CompileSpec == nil || art.CompileSpec.Package == nil {
+			continue
+		}
+		key := art.CompileSpec.Package.HumanReadableString()
+		mPkgs[key] = append(mPkgs[key], m)
+	}
+
+	for _, metas := range mPkgs {
+		if len(metas) < 2 {
+			continue
+		}
+
+		sort.Slice(metas, func(i, j int) bool {
+			return c.isPreferredArtifact(metas[i], metas[j])
+		})
+
+		best := metas[0]
+		bestTarball := filepath.Base(c.MetaMap[best].Path)
+		for _, m := range metas[1:] {
+			c.Duplicates[m] = best
+			tarball := filepath.Base(c.MetaMap[m].Path)
+			if tarball != bestTarball {
+				if _, ok := c.PkgsMap[tarball]; ok {
+					c.Duplicates[tarball] = best
+				}
+			}
+		}
+	}
+
+	// Tarballs with a valid metafile name that reference another tarball,
+	// for example after a change of the compression.
+	for t, m := range c.PkgsMap {
+		if _, ok := c.Removals[t]; ok {
+			continue
+		}
+		if _, ok := referenced[t]; ok {
+			continue
+		}
+		if _, ok := c.MetaMap[m]; ok {
+			if best, ok := c.Duplicates[m]; ok {
+				c.Duplicates[t] = best
+			} else {
+				c.Duplicates[t] = m
+			}
+		}
+	}
+
+	files := []string{}
+	for f := range c.Duplicates {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+
+	policy := c.Specs.GetCleaner().GetDuplicatesPolicy()
+	for _, f := range files {
+		best := c.Duplicates[f]
+		if policy == specs.DuplicatesPolicyDelete {
+			if c.Verbose {
+				InfoC(fmt.Sprintf("[%s] Duplicate of %s. I will delete it.", f, best))
+			} else {
+				DebugC(fmt.Sprintf("[%s] Duplicate of %s. I will delete it.", f, best))
+			}
+			c.markRemove(f, ReasonDuplicate)
+		} else {
+			Warning(fmt.Sprintf("[%s] Duplicate of %s.", f, best))
+		}
+	}
+}
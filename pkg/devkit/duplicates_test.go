@@ -0,0 +1,75 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"testing"
+
+	specs "github.com/macaroni-os/anise-repo-devkit/pkg/specs"
+)
+
+var duplicateFixtures = []*fixture{
+	{Category: "app", Name: "foo", Version: "1.0", Compression: "gz",
+		FileName: "foo-app-1.0-gz", BuildTimestamp: "1700000000",
+		InTree: true, WithMeta: true, WithTarball: true},
+	{Category: "app", Name: "foo", Version: "1.0", Compression: "zst",
+		BuildTimestamp: "1600000000",
+		InTree:         true, WithMeta: true, WithTarball: true},
+}
+
+func TestAnalyzeDuplicatesWarn(t *testing.T) {
+	knife, _ := newFixtureKnife(t, specs.NewAniseRDConfig(), duplicateFixtures)
+	if err := knife.Analyze(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	assertFiles(t, knife.Files2Remove)
+
+	// The newest build is preferred without compression preference.
+	if knife.Duplicates[duplicateFixtures[1].MetaFile()] != duplicateFixtures[0].MetaFile() {
+		t.Fatalf("Unexpected duplicates %v", knife.Duplicates)
+	}
+}
+
+func TestAnalyzeDuplicatesDelete(t *testing.T) {
+	s := specs.NewAniseRDConfig()
+	s.Cleaner.DuplicatesPolicy = specs.DuplicatesPolicyDelete
+	s.Cleaner.CompressionPreference = []string{"zstd", "gzip"}
+
+	files := analyzeFixtures(t, s, duplicateFixtures)
+	assertFiles(t, files,
+		duplicateFixtures[0].MetaFile(), duplicateFixtures[0].Tarball())
+}
+
+func TestAnalyzeStaleTarball(t *testing.T) {
+	fixtures := []*fixture{
+		{Category: "app", Name: "foo", Version: "1.0", Compression: "zst",
+			InTree: true, WithMeta: true, WithTarball: true},
+	}
+
+	s := specs.NewAniseRDConfig()
+	s.Cleaner.DuplicatesPolicy = specs.DuplicatesPolicyDelete
+
+	// Old gzip tarball replaced by the zstd build.
+	stale := "foo-app-1.0.package.tar.gz"
+	files := analyzeFixtures(t, s, fixtures, stale)
+	assertFiles(t, files, stale)
+}
+
+func TestParseBuildTimestamp(t *testing.T) {
+	for _, ts := range []string{
+		"1700000000",
+		"2023-11-14 22:13:20.123456789 +0000 UTC m=+0.001",
+		"2023-11-14T22:13:20Z",
+	} {
+		if parseBuildTimestamp(ts).Unix() != 1700000000 {
+			t.Errorf("Unexpected time for %s: %s", ts, parseBuildTimestamp(ts))
+		}
+	}
+
+	if !parseBuildTimestamp("invalid").IsZero() {
+		t.Error("Expected zero time on invalid timestamp")
+	}
+}
@@ -0,0 +1,180 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	anise_pkg "github.com/geaaru/luet/pkg/package"
+)
+
+const (
+	HiddenInclude = "include"
+	HiddenExclude = "exclude"
+	HiddenOnly    = "only"
+)
+
+// KeySelector matches a label or an annotation by key and
+// optionally by value.
+type KeySelector struct {
+	Key      string
+	Value    string
+	HasValue bool
+}
+
+// PkgsFilter selects the packages of a list. A package is selected if
+// its package name (name-category) matches one of the regexes, its category is one of the
+// categories and it matches all labels and annotations selectors.
+// Empty selectors match all packages.
+type PkgsFilter struct {
+	Regexes     []*regexp.Regexp
+	Categories  []string
+	Labels      []KeySelector
+	Annotations []KeySelector
+	// Selection of the hidden packages: include|exclude|only.
+	Hidden string
+}
+
+// ParseKeySelector parses a selector in the format key or key=value.
+func ParseKeySelector(s string) (KeySelector, error) {
+	ans := KeySelector{}
+	key, value, found := strings.Cut(s, "=")
+	if key == "" {
+		return ans, errors.New(fmt.Sprintf("Invalid selector %s: key is empty", s))
+	}
+
+	ans.Key = key
+	ans.Value = value
+	ans.HasValue = found
+	return ans, nil
+}
+
+// NewPkgsFilter returns a filter with the regexes of the package names,
+// the categories, the labels and annotations selectors in the format
+// key or key=value and the hidden selection.
+func NewPkgsFilter(regexes, categories, labels, annotations []string,
+	hidden string) (*PkgsFilter, error) {
+
+	ans := &PkgsFilter{
+		Regexes:     []*regexp.Regexp{},
+		Categories:  categories,
+		Labels:      []KeySelector{},
+		Annotations: []KeySelector{},
+		Hidden:      hidden,
+	}
+
+	if ans.Hidden == "" {
+		ans.Hidden = HiddenInclude
+	}
+	switch ans.Hidden {
+	case HiddenInclude, HiddenExclude, HiddenOnly:
+	default:
+		return nil, errors.New(fmt.Sprintf("Invalid hidden value %s", hidden))
+	}
+
+	for _, f := range regexes {
+		r, err := regexp.Compile(f)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid regex %s: %s", f, err.Error()))
+		}
+		ans.Regexes = append(ans.Regexes, r)
+	}
+
+	for _, l := range labels {
+		s, err := ParseKeySelector(l)
+		if err != nil {
+			return nil, err
+		}
+		ans.Labels = append(ans.Labels, s)
+	}
+
+	for _, a := range annotations {
+		s, err := ParseKeySelector(a)
+		if err != nil {
+			return nil, err
+		}
+		ans.Annotations = append(ans.Annotations, s)
+	}
+
+	return ans, nil
+}
+
+func (s *KeySelector) match(value string, exists bool) bool {
+	return exists && (!s.HasValue || s.Value == value)
+}
+
+// Match returns true if the package is selected by the filter.
+func (f *PkgsFilter) Match(p *anise_pkg.DefaultPackage) bool {
+	switch f.Hidden {
+	case HiddenExclude:
+		if p.Hidden {
+			return false
+		}
+	case HiddenOnly:
+		if !p.Hidden {
+			return false
+		}
+	}
+
+	if len(f.Categories) > 0 {
+		found := false
+		for _, c := range f.Categories {
+			if c == p.GetCategory() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(f.Regexes) > 0 {
+		found := false
+		for _, r := range f.Regexes {
+			if r.MatchString(p.GetPackageName()) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	for _, s := range f.Labels {
+		v, ok := p.Labels[s.Key]
+		if !s.match(v, ok) {
+			return false
+		}
+	}
+
+	for _, s := range f.Annotations {
+		v, ok := p.Annotations[s.Key]
+		value := ""
+		if ok && v != nil {
+			value = fmt.Sprintf("%v", v)
+		}
+		if !s.match(value, ok) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Filter returns the packages of the list selected by the filter.
+func (f *PkgsFilter) Filter(list []*anise_pkg.DefaultPackage) []*anise_pkg.DefaultPackage {
+	ans := []*anise_pkg.DefaultPackage{}
+	for _, p := range list {
+		if f.Match(p) {
+			ans = append(ans, p)
+		}
+	}
+	return ans
+}
@@ -0,0 +1,72 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"testing"
+
+	anise_pkg "github.com/geaaru/luet/pkg/package"
+)
+
+func TestPkgsFilter(t *testing.T) {
+	newPkg := func(cat, name string, labels map[string]string,
+		annotations map[string]interface{}, hidden bool) *anise_pkg.DefaultPackage {
+		p := anise_pkg.NewPackageWithCat(cat, name, "1.0",
+			[]*anise_pkg.DefaultPackage{}, []*anise_pkg.DefaultPackage{})
+		p.Labels = labels
+		p.Annotations = annotations
+		p.Hidden = hidden
+		return p
+	}
+
+	list := []*anise_pkg.DefaultPackage{
+		newPkg("app", "foo", map[string]string{"kit": "core-kit"},
+			map[string]interface{}{"stage": 3}, false),
+		newPkg("app", "bar", map[string]string{"kit": "python-kit"}, nil, false),
+		newPkg("lib", "baz", map[string]string{"kit": "core-kit", "emerge.packages": "lib/baz"},
+			nil, true),
+		newPkg("sys", "qux", nil, map[string]interface{}{"stage": 4}, false),
+	}
+
+	names := func(l []*anise_pkg.DefaultPackage) []string {
+		ans := []string{}
+		for _, p := range l {
+			ans = append(ans, p.GetCategory()+"/"+p.GetName())
+		}
+		return ans
+	}
+
+	for _, tc := range []struct {
+		Regexes, Categories, Labels, Annotations []string
+		Hidden                                   string
+		Expected                                 []string
+	}{
+		{Expected: []string{"app/foo", "app/bar", "lib/baz", "sys/qux"}},
+		{Regexes: []string{"-app$", "qux"}, Expected: []string{"app/foo", "app/bar", "sys/qux"}},
+		{Categories: []string{"lib", "sys"}, Expected: []string{"lib/baz", "sys/qux"}},
+		{Labels: []string{"kit=core-kit"}, Expected: []string{"app/foo", "lib/baz"}},
+		{Labels: []string{"kit=core-kit", "emerge.packages"}, Expected: []string{"lib/baz"}},
+		{Labels: []string{"kit"}, Hidden: HiddenExclude, Expected: []string{"app/foo", "app/bar"}},
+		{Hidden: HiddenOnly, Expected: []string{"lib/baz"}},
+		{Annotations: []string{"stage"}, Expected: []string{"app/foo", "sys/qux"}},
+		{Annotations: []string{"stage=4"}, Categories: []string{"sys"}, Expected: []string{"sys/qux"}},
+	} {
+		f, err := NewPkgsFilter(tc.Regexes, tc.Categories, tc.Labels, tc.Annotations, tc.Hidden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertFiles(t, names(f.Filter(list)), tc.Expected...)
+	}
+
+	if _, err := NewPkgsFilter([]string{"("}, nil, nil, nil, ""); err == nil {
+		t.Error("Expected error with an invalid regex")
+	}
+	if _, err := NewPkgsFilter(nil, nil, []string{"=foo"}, nil, ""); err == nil {
+		t.Error("Expected error with an invalid label selector")
+	}
+	if _, err := NewPkgsFilter(nil, nil, nil, nil, "all"); err == nil {
+		t.Error("Expected error with an invalid hidden value")
+	}
+}
@@ -0,0 +1,206 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/macaroni-os/anise-repo-devkit/pkg/backends"
+	specs "github.com/macaroni-os/anise-repo-devkit/pkg/specs"
+
+	compilerspec "github.com/geaaru/luet/pkg/compiler/types/spec"
+	. "github.com/geaaru/luet/pkg/logger"
+	anise_pkg "github.com/geaaru/luet/pkg/package"
+	artifact "github.com/geaaru/luet/pkg/v2/compiler/types/artifact"
+	compression "github.com/geaaru/luet/pkg/v2/compiler/types/compression"
+)
+
+func TestMain(m *testing.M) {
+	InitAurora()
+	os.Exit(m.Run())
+}
+
+// fixture describes a package of the tree and/or of the repository.
+type fixture struct {
+	Category  string
+	Name      string
+	Version   string
+	Requires  []*anise_pkg.DefaultPackage
+	Conflicts []*anise_pkg.DefaultPackage
+	Provides  []*anise_pkg.DefaultPackage
+
+	// The package is present in the tree.
+	InTree bool
+	// The metadata file is present in the repository.
+	WithMeta bool
+	// The package tarball is present in the repository.
+	WithTarball bool
+	// Compression extension of the tarball.
+	Compression string
+	// Optional base name of the metadata and tarball files.
+	// Default is the package fingerprint.
+	FileName string
+	// Optional build timestamp of the artifact.
+	BuildTimestamp string
+	// Files shipped by the artifact.
+	Files []string
+	// Optional license of the package.
+	License string
+}
+
+func (f *fixture) Package() *anise_pkg.DefaultPackage {
+	conflicts := f.Conflicts
+	if conflicts == nil {
+		conflicts = []*anise_pkg.DefaultPackage{}
+	}
+	ans := anise_pkg.NewPackageWithCat(f.Category, f.Name, f.Version,
+		f.Requires, conflicts)
+	ans.SetLicense(f.License)
+	ans.Provides = f.Provides
+	return ans
+}
+
+func (f *fixture) baseName() string {
+	if f.FileName != "" {
+		return f.FileName
+	}
+	return f.Package().GetFingerPrint()
+}
+
+func (f *fixture) MetaFile() string {
+	return f.baseName() + ".metadata.yaml"
+}
+
+func (f *fixture) Tarball() string {
+	ext := ".package.tar"
+	if f.Compression != "" {
+		ext += "." + f.Compression
+	}
+	return f.baseName() + ext
+}
+
+func (f *fixture) Artifact() *artifact.PackageArtifact {
+	art := artifact.NewPackageArtifact(f.Tarball())
+	art.CompileSpec = &compilerspec.LuetCompilationSpec{
+		Package: f.Package(),
+	}
+	art.CompileSpec.Package.SetBuildTimestamp(f.BuildTimestamp)
+	art.Files = f.Files
+
+	switch f.Compression {
+	case "zst":
+		art.CompressionType = compression.Zstandard
+	case "gz":
+		art.CompressionType = compression.GZip
+	}
+	return art
+}
+
+// newFixtureTree writes the definition.yaml files of the fixtures
+// available in the tree and returns the path of the tree.
+func newFixtureTree(t *testing.T, fixtures []*fixture) string {
+	t.Helper()
+
+	treeDir := t.TempDir()
+	for _, f := range fixtures {
+		if !f.InTree {
+			continue
+		}
+
+		p := f.Package()
+		dir := filepath.Join(treeDir, p.GetCategory(), p.GetName(), p.GetVersion())
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := p.Yaml()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = os.WriteFile(filepath.Join(dir, anise_pkg.PackageDefinitionFile), data, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return treeDir
+}
+
+// newFixtureBackend creates a memory backend with the repository
+// files of the fixtures.
+func newFixtureBackend(t *testing.T, s *specs.AniseRDConfig,
+	fixtures []*fixture, extraFiles ...string) *backends.BackendMemory {
+	t.Helper()
+
+	b := backends.NewBackendMemory(s)
+	for _, f := range fixtures {
+		if f.WithMeta {
+			if err := b.AddArtifact(f.MetaFile(), f.Artifact(), false); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if f.WithTarball {
+			b.AddFile(f.Tarball(), []byte{})
+		}
+	}
+
+	for _, e := range extraFiles {
+		b.AddFile(e, []byte{})
+	}
+
+	return b
+}
+
+// newFixtureKnife returns a knife with the fixtures tree loaded.
+func newFixtureKnife(t *testing.T, s *specs.AniseRDConfig,
+	fixtures []*fixture, extraFiles ...string) (*RepoKnife, *backends.BackendMemory) {
+	t.Helper()
+
+	b := newFixtureBackend(t, s, fixtures, extraFiles...)
+	knife := NewRepoKnifeWithBackend(s, b)
+	if err := knife.LoadTrees([]string{newFixtureTree(t, fixtures)}); err != nil {
+		t.Fatal(err)
+	}
+
+	return knife, b
+}
+
+func analyzeFixtures(t *testing.T, s *specs.AniseRDConfig,
+	fixtures []*fixture, extraFiles ...string) []string {
+	t.Helper()
+
+	knife, _ := newFixtureKnife(t, s, fixtures, extraFiles...)
+	if err := knife.Analyze(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	return sortedCopy(knife.Files2Remove)
+}
+
+func sortedCopy(l []string) []string {
+	ans := append([]string{}, l...)
+	sort.Strings(ans)
+	return ans
+}
+
+func assertFiles(t *testing.T, got []string, expected ...string) {
+	t.Helper()
+
+	got = sortedCopy(got)
+	expected = sortedCopy(expected)
+	if len(got) != len(expected) {
+		t.Fatalf("Expected files %v, got %v", expected, got)
+	}
+	for idx := range got {
+		if got[idx] != expected[idx] {
+			t.Fatalf("Expected files %v, got %v", expected, got)
+		}
+	}
+}
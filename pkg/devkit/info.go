@@ -0,0 +1,135 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	anise_pkg "github.com/geaaru/luet/pkg/package"
+	artifact "github.com/geaaru/luet/pkg/v2/compiler/types/artifact"
+)
+
+// ArtifactInfo describes an artifact of the repository.
+type ArtifactInfo struct {
+	Package         string                 `json:"package" yaml:"package"`
+	MetaFile        string                 `json:"metafile" yaml:"metafile"`
+	Path            string                 `json:"path" yaml:"path"`
+	Size            int64                  `json:"size,omitempty" yaml:"size,omitempty"`
+	CompressionType string                 `json:"compression_type" yaml:"compression_type"`
+	Checksums       map[string]string      `json:"checksums,omitempty" yaml:"checksums,omitempty"`
+	Requires        []string               `json:"requires,omitempty" yaml:"requires,omitempty"`
+	Conflicts       []string               `json:"conflicts,omitempty" yaml:"conflicts,omitempty"`
+	Provides        []string               `json:"provides,omitempty" yaml:"provides,omitempty"`
+	Labels          map[string]string      `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Annotations     map[string]interface{} `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+	BuildTimestamp  string                 `json:"build_timestamp,omitempty" yaml:"build_timestamp,omitempty"`
+	Files           int                    `json:"files" yaml:"files"`
+	// Path of the tree definition of the package if available.
+	TreeDefinition string `json:"tree_definition,omitempty" yaml:"tree_definition,omitempty"`
+}
+
+var pkgStrRegex = regexp.MustCompile(`^([^/]+)/(.+?)(?:-([0-9][^-]*(?:-r[0-9]+)?))?$`)
+
+// ParsePackageStr parses a package string in the format
+// category/name[-version].
+func ParsePackageStr(s string) (*anise_pkg.DefaultPackage, error) {
+	m := pkgStrRegex.FindStringSubmatch(s)
+	if m == nil {
+		return nil, errors.New(fmt.Sprintf(
+			"Invalid package %s: the format is category/name[-version]", s))
+	}
+
+	ans := anise_pkg.NewPackageWithCat(m[1], m[2], m[3],
+		[]*anise_pkg.DefaultPackage{}, []*anise_pkg.DefaultPackage{})
+	return ans, nil
+}
+
+func pkgsStrings(pkgs []*anise_pkg.DefaultPackage) []string {
+	ans := []string{}
+	for _, p := range pkgs {
+		s := p.GetCategory() + "/" + p.GetName()
+		if p.GetVersion() != "" {
+			s += " " + p.GetVersion()
+		}
+		ans = append(ans, s)
+	}
+	return ans
+}
+
+// NewArtifactInfo returns the information of the artifact of a metafile.
+func (c *RepoKnife) NewArtifactInfo(metaFile string, art *artifact.PackageArtifact) *ArtifactInfo {
+	p := art.GetPackage()
+	tarball := filepath.Base(art.Path)
+
+	ans := &ArtifactInfo{
+		Package:         p.HumanReadableString(),
+		MetaFile:        metaFile,
+		Path:            tarball,
+		Size:            c.FilesSize[tarball],
+		CompressionType: string(art.CompressionType),
+		Checksums:       art.Checksums,
+		Requires:        pkgsStrings(p.GetRequires()),
+		Conflicts:       pkgsStrings(p.GetConflicts()),
+		Provides:        pkgsStrings(p.Provides),
+		Labels:          p.Labels,
+		Annotations:     p.Annotations,
+		BuildTimestamp:  p.GetBuildTimestamp(),
+		Files:           len(art.Files),
+	}
+
+	if c.ReciperRuntime != nil {
+		tp, _ := c.ReciperRuntime.GetDatabase().FindPackage(p)
+		if tp != nil && tp.GetPath() != "" {
+			ans.TreeDefinition = filepath.Join(tp.GetPath(),
+				anise_pkg.PackageDefinitionFile)
+		}
+	}
+
+	return ans
+}
+
+// GetArtifactsInfo returns the information of the artifacts that match
+// the package. If the version of the package is empty all versions are
+// returned. Only the metadata files with a name that contains the name
+// of the package are downloaded.
+func (c *RepoKnife) GetArtifactsInfo(ctx context.Context, pkg *anise_pkg.DefaultPackage) ([]*ArtifactInfo, error) {
+	ans := []*ArtifactInfo{}
+
+	err := c.LoadMetadata(ctx, func(f string) bool {
+		return ClassifyFile(f) != FileClassMetadata ||
+			strings.Contains(f, pkg.GetName())
+	})
+	if err != nil {
+		return ans, err
+	}
+
+	metas := []string{}
+	for m := range c.MetaMap {
+		metas = append(metas, m)
+	}
+	sort.Strings(metas)
+
+	for _, m := range metas {
+		art := c.MetaMap[m]
+		p := art.GetPackage()
+		if p == nil || p.GetCategory() != pkg.GetCategory() ||
+			p.GetName() != pkg.GetName() {
+			continue
+		}
+		if pkg.GetVersion() != "" && p.GetVersion() != pkg.GetVersion() {
+			continue
+		}
+
+		ans = append(ans, c.NewArtifactInfo(m, art))
+	}
+
+	return ans, nil
+}
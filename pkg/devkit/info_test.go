@@ -0,0 +1,86 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	specs "github.com/macaroni-os/anise-repo-devkit/pkg/specs"
+
+	anise_pkg "github.com/geaaru/luet/pkg/package"
+)
+
+func TestParsePackageStr(t *testing.T) {
+	for s, expected := range map[string][3]string{
+		"app/foo":           {"app", "foo", ""},
+		"app/foo-1.0":       {"app", "foo", "1.0"},
+		"app/foo-bar-1.0":   {"app", "foo-bar", "1.0"},
+		"app/foo-1.0.2-r1":  {"app", "foo", "1.0.2-r1"},
+		"dev-lang/python-3": {"dev-lang", "python", "3"},
+	} {
+		p, err := ParsePackageStr(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.GetCategory() != expected[0] || p.GetName() != expected[1] ||
+			p.GetVersion() != expected[2] {
+			t.Errorf("Unexpected package %v for %s", p, s)
+		}
+	}
+
+	if _, err := ParsePackageStr("foo"); err == nil {
+		t.Error("Expected error without category")
+	}
+}
+
+func TestGetArtifactsInfo(t *testing.T) {
+	fixtures := []*fixture{
+		{Category: "app", Name: "foo", Version: "1.0", Compression: "zst",
+			InTree: false, WithMeta: true, WithTarball: true},
+		{Category: "app", Name: "foo", Version: "1.1", Compression: "zst",
+			Requires: []*anise_pkg.DefaultPackage{
+				{Category: "app", Name: "bar", Version: ">=1.0"},
+			},
+			InTree: true, WithMeta: true, WithTarball: true},
+		{Category: "app", Name: "bar", Version: "1.0", Compression: "zst",
+			InTree: true, WithMeta: true, WithTarball: true},
+	}
+
+	knife, b := newFixtureKnife(t, specs.NewAniseRDConfig(), fixtures)
+
+	infos, err := knife.GetArtifactsInfo(context.Background(), fixtures[0].Package())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 || infos[0].Package != "app/foo-1.0" ||
+		infos[0].TreeDefinition != "" || infos[0].CompressionType != "zstd" {
+		t.Fatalf("Unexpected infos %+v", infos)
+	}
+
+	pkg, _ := ParsePackageStr("app/foo")
+	infos, err = knife.GetArtifactsInfo(context.Background(), pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 {
+		t.Fatalf("Expected 2 artifacts, got %d", len(infos))
+	}
+
+	info := infos[1]
+	if info.Package != "app/foo-1.1" || len(info.Requires) != 1 ||
+		info.Requires[0] != "app/bar >=1.0" ||
+		filepath.Base(info.TreeDefinition) != anise_pkg.PackageDefinitionFile {
+		t.Fatalf("Unexpected info %+v", info)
+	}
+
+	// Only the metadata of the package are downloaded.
+	for _, c := range b.GetCalls("GetMetadata") {
+		if c.File == fixtures[2].MetaFile() {
+			t.Fatalf("Unexpected download of %s", c.File)
+		}
+	}
+}
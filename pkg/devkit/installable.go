@@ -0,0 +1,277 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	. "github.com/geaaru/luet/pkg/logger"
+	anise_pkg "github.com/geaaru/luet/pkg/package"
+	"github.com/geaaru/luet/pkg/solver"
+)
+
+// InstallCheck is the result of the installability check
+// of a package or of a set of packages.
+type InstallCheck struct {
+	Packages    []string `json:"packages" yaml:"packages"`
+	Installable bool     `json:"installable" yaml:"installable"`
+	// Requirements of the packages selected by the solver that are not
+	// satisfied. When the solver fails, the requirements not satisfied
+	// by any artifact of the repository.
+	Missing []string `json:"missing,omitempty" yaml:"missing,omitempty"`
+	// Explanation of the solver for the unsatisfiable constraints.
+	Explanation string `json:"explanation,omitempty" yaml:"explanation,omitempty"`
+}
+
+// newArtifactsDatabase returns a database with the packages
+// of the loaded artifacts.
+func (c *RepoKnife) newArtifactsDatabase() (anise_pkg.PackageDatabase, error) {
+	db := anise_pkg.NewInMemoryDatabase(false)
+
+	for _, m := range c.sortedMetas() {
+		p := artifactPackage(c.MetaMap[m])
+		if p == nil {
+			continue
+		}
+		if _, err := db.CreatePackage(p); err != nil {
+			return nil, errors.New(fmt.Sprintf(
+				"Error on add package %s to database: %s",
+				p.HumanReadableString(), err.Error()))
+		}
+	}
+
+	return db, nil
+}
+
+func (c *RepoKnife) sortedMetas() []string {
+	ans := []string{}
+	for m := range c.MetaMap {
+		ans = append(ans, m)
+	}
+	sort.Strings(ans)
+	return ans
+}
+
+// resolveTarget returns the latest package of the artifacts
+// that matches the target. The version of the target is optional.
+func (c *RepoKnife) resolveTarget(target *anise_pkg.DefaultPackage) *anise_pkg.DefaultPackage {
+	var ans *anise_pkg.DefaultPackage
+	for _, m := range c.sortedMetas() {
+		p := artifactPackage(c.MetaMap[m])
+		if p == nil || p.GetCategory() != target.GetCategory() ||
+			p.GetName() != target.GetName() {
+			continue
+		}
+		if target.GetVersion() != "" && p.GetVersion() != target.GetVersion() {
+			continue
+		}
+		if ans == nil || isNewerVersion(p.GetVersion(), ans.GetVersion()) {
+			ans = p
+		}
+	}
+	return ans
+}
+
+// newArtifactsIndex returns the packages of the loaded artifacts
+// indexed by category/name and by the category/name of every
+// package that they provide.
+func (c *RepoKnife) newArtifactsIndex() map[string][]*anise_pkg.DefaultPackage {
+	ans := make(map[string][]*anise_pkg.DefaultPackage, 0)
+	for _, m := range c.sortedMetas() {
+		p := artifactPackage(c.MetaMap[m])
+		if p == nil {
+			continue
+		}
+		key := p.GetCategory() + "/" + p.GetName()
+		ans[key] = append(ans[key], p)
+		for _, prov := range p.Provides {
+			key = prov.GetCategory() + "/" + prov.GetName()
+			ans[key] = append(ans[key], p)
+		}
+	}
+	return ans
+}
+
+// missingRequires returns the requirements of the packages and of their
+// dependencies that are not satisfied by any artifact. It's used only to
+// explain why the solver fails, so every artifact that satisfies a
+// requirement is visited.
+func missingRequires(pkgs []*anise_pkg.DefaultPackage,
+	index map[string][]*anise_pkg.DefaultPackage) []string {
+	ans := []string{}
+	visited := make(map[string]bool, 0)
+	queue := append([]*anise_pkg.DefaultPackage{}, pkgs...)
+
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		if visited[p.HumanReadableString()] {
+			continue
+		}
+		visited[p.HumanReadableString()] = true
+
+		for _, req := range p.GetRequires() {
+			found := false
+			for _, mp := range index[req.GetCategory()+"/"+req.GetName()] {
+				if packageSatisfies(req, mp) {
+					queue = append(queue, mp)
+					found = true
+				}
+			}
+			if !found {
+				ans = append(ans, fmt.Sprintf("%s requires %s/%s %s",
+					p.HumanReadableString(), req.GetCategory(), req.GetName(),
+					req.GetVersion()))
+			}
+		}
+	}
+
+	return ans
+}
+
+func solveInstall(db anise_pkg.PackageDatabase,
+	pkgs []*anise_pkg.DefaultPackage,
+	index map[string][]*anise_pkg.DefaultPackage) *InstallCheck {
+
+	ans := &InstallCheck{
+		Packages:    []string{},
+		Installable: true,
+	}
+
+	wanted := anise_pkg.Packages{}
+	for _, p := range pkgs {
+		ans.Packages = append(ans.Packages, p.HumanReadableString())
+		wanted = append(wanted, p)
+	}
+
+	s := solver.NewSolver(solver.Options{Type: solver.SingleCoreSimple},
+		anise_pkg.NewInMemoryDatabase(false), db,
+		anise_pkg.NewInMemoryDatabase(false))
+
+	assertions, err := s.RelaxedInstall(wanted)
+	if err != nil {
+		ans.Installable = false
+		ans.Explanation = strings.TrimSpace(err.Error())
+		ans.Missing = missingRequires(pkgs, index)
+		return ans
+	}
+
+	selected := make(map[string]bool, 0)
+	selectedPkgs := []anise_pkg.Package{}
+	for _, a := range assertions {
+		if a.Value {
+			selected[a.Package.GetFingerPrint()] = true
+			selectedPkgs = append(selectedPkgs, a.Package)
+		}
+	}
+
+	// Check that the wanted packages are selected by the solver.
+	for _, p := range pkgs {
+		if !selected[p.GetFingerPrint()] {
+			ans.Installable = false
+			ans.Explanation = fmt.Sprintf("The package %s is not selected by the solver.",
+				p.HumanReadableString())
+		}
+	}
+
+	// The solver ignores the requirements without candidates, so the
+	// requirements of the packages selected are checked against the
+	// others packages selected.
+	ans.Missing = unsatisfiedRequires(selectedPkgs, selected, index)
+	if len(ans.Missing) > 0 {
+		ans.Installable = false
+	}
+
+	return ans
+}
+
+// unsatisfiedRequires returns the requirements of the packages selected
+// by the solver that are not satisfied by another selected package.
+func unsatisfiedRequires(pkgs []anise_pkg.Package, selected map[string]bool,
+	index map[string][]*anise_pkg.DefaultPackage) []string {
+	ans := []string{}
+
+	sort.Slice(pkgs, func(i, j int) bool {
+		return pkgs[i].HumanReadableString() < pkgs[j].HumanReadableString()
+	})
+
+	for _, p := range pkgs {
+		for _, req := range p.GetRequires() {
+			found := false
+			for _, mp := range index[req.GetCategory()+"/"+req.GetName()] {
+				if selected[mp.GetFingerPrint()] && packageSatisfies(req, mp) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				ans = append(ans, fmt.Sprintf("%s requires %s/%s %s",
+					p.HumanReadableString(), req.GetCategory(), req.GetName(),
+					req.GetVersion()))
+			}
+		}
+	}
+
+	return ans
+}
+
+// CheckInstallable checks if the packages are installable with the
+// artifacts of the repository. Without targets the latest version of
+// every package is checked. If together is true the targets are solved
+// as a single set, otherwise every package is solved alone.
+func (c *RepoKnife) CheckInstallable(ctx context.Context,
+	targets []*anise_pkg.DefaultPackage, together bool) ([]*InstallCheck, error) {
+	ans := []*InstallCheck{}
+
+	err := c.LoadMetadata(ctx, nil)
+	if err != nil {
+		return ans, err
+	}
+
+	db, err := c.newArtifactsDatabase()
+	if err != nil {
+		return ans, err
+	}
+
+	pkgs := []*anise_pkg.DefaultPackage{}
+	if len(targets) == 0 {
+		for _, m := range c.getLatestArtifacts() {
+			pkgs = append(pkgs, artifactPackage(c.MetaMap[m]))
+		}
+	} else {
+		for _, t := range targets {
+			p := c.resolveTarget(t)
+			if p == nil {
+				return ans, errors.New(fmt.Sprintf(
+					"No artifacts found for the package %s", t.HumanReadableString()))
+			}
+			pkgs = append(pkgs, p)
+		}
+	}
+
+	index := c.newArtifactsIndex()
+	if together {
+		ans = append(ans, solveInstall(db, pkgs, index))
+		return ans, nil
+	}
+
+	for _, p := range pkgs {
+		if ctx.Err() != nil {
+			return ans, ctx.Err()
+		}
+
+		check := solveInstall(db, []*anise_pkg.DefaultPackage{p}, index)
+		if !check.Installable {
+			DebugC(fmt.Sprintf("[%s] Not installable.", p.HumanReadableString()))
+		}
+		ans = append(ans, check)
+	}
+
+	return ans, nil
+}
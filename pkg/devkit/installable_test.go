@@ -0,0 +1,162 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"context"
+	"testing"
+
+	specs "github.com/macaroni-os/anise-repo-devkit/pkg/specs"
+
+	anise_pkg "github.com/geaaru/luet/pkg/package"
+)
+
+var installableFixtures = []*fixture{
+	{Category: "app", Name: "foo", Version: "1.0", Compression: "zst",
+		Requires: []*anise_pkg.DefaultPackage{
+			{Category: "lib", Name: "bar", Version: ">=1.0"},
+		},
+		InTree: true, WithMeta: true, WithTarball: true},
+	{Category: "lib", Name: "bar", Version: "1.0", Compression: "zst",
+		InTree: true, WithMeta: true, WithTarball: true},
+	{Category: "app", Name: "baz", Version: "1.0", Compression: "zst",
+		Requires: []*anise_pkg.DefaultPackage{
+			{Category: "lib", Name: "qux", Version: ">=1.0"},
+		},
+		InTree: true, WithMeta: true, WithTarball: true},
+	{Category: "app", Name: "top", Version: "1.0", Compression: "zst",
+		Requires: []*anise_pkg.DefaultPackage{
+			{Category: "app", Name: "baz", Version: ">=1.0"},
+		},
+		InTree: true, WithMeta: true, WithTarball: true},
+	{Category: "app", Name: "alt", Version: "1.0", Compression: "zst",
+		Conflicts: []*anise_pkg.DefaultPackage{
+			{Category: "lib", Name: "bar", Version: ">=0"},
+		},
+		InTree: true, WithMeta: true, WithTarball: true},
+}
+
+func TestCheckInstallable(t *testing.T) {
+	knife, _ := newFixtureKnife(t, specs.NewAniseRDConfig(), installableFixtures)
+
+	checks, err := knife.CheckInstallable(context.Background(), nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checks) != len(installableFixtures) {
+		t.Fatalf("Expected %d checks, got %d", len(installableFixtures), len(checks))
+	}
+
+	result := make(map[string]*InstallCheck, 0)
+	for _, c := range checks {
+		result[c.Packages[0]] = c
+	}
+
+	for p, installable := range map[string]bool{
+		"app/foo-1.0": true,
+		"lib/bar-1.0": true,
+		"app/alt-1.0": true,
+		"app/baz-1.0": false,
+		"app/top-1.0": false,
+	} {
+		if result[p].Installable != installable {
+			t.Errorf("Package %s: expected installable %v, got %+v", p, installable, result[p])
+		}
+	}
+
+	if len(result["app/top-1.0"].Missing) != 1 {
+		t.Errorf("Expected the missing lib/qux, got %v", result["app/top-1.0"].Missing)
+	}
+}
+
+func TestCheckInstallableTogether(t *testing.T) {
+	knife, _ := newFixtureKnife(t, specs.NewAniseRDConfig(), installableFixtures)
+
+	foo, _ := ParsePackageStr("app/foo")
+	alt, _ := ParsePackageStr("app/alt-1.0")
+
+	checks, err := knife.CheckInstallable(context.Background(),
+		[]*anise_pkg.DefaultPackage{foo, alt}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checks) != 1 || checks[0].Installable || checks[0].Explanation == "" {
+		t.Fatalf("Expected conflict between app/foo and app/alt, got %+v", checks)
+	}
+
+	missing, _ := ParsePackageStr("app/missing")
+	if _, err := knife.CheckInstallable(context.Background(),
+		[]*anise_pkg.DefaultPackage{missing}, false); err == nil {
+		t.Fatal("Expected error on missing package")
+	}
+}
+
+func TestCheckInstallableBrokenOldVersion(t *testing.T) {
+	fixtures := []*fixture{
+		{Category: "app", Name: "foo", Version: "1.0", Compression: "zst",
+			Requires: []*anise_pkg.DefaultPackage{
+				{Category: "lib", Name: "bar", Version: ">=1.0"},
+			},
+			InTree: true, WithMeta: true, WithTarball: true},
+		// The old version of the dependency has a broken requirement.
+		{Category: "lib", Name: "bar", Version: "1.0", Compression: "zst",
+			Requires: []*anise_pkg.DefaultPackage{
+				{Category: "lib", Name: "missing", Version: ">=1.0"},
+			},
+			InTree: true, WithMeta: true, WithTarball: true},
+		{Category: "lib", Name: "bar", Version: "2.0", Compression: "zst",
+			InTree: true, WithMeta: true, WithTarball: true},
+	}
+	knife, _ := newFixtureKnife(t, specs.NewAniseRDConfig(), fixtures)
+
+	foo, _ := ParsePackageStr("app/foo")
+	checks, err := knife.CheckInstallable(context.Background(),
+		[]*anise_pkg.DefaultPackage{foo}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checks) != 1 || !checks[0].Installable || len(checks[0].Missing) != 0 {
+		t.Fatalf("Expected app/foo installable with lib/bar-2.0, got %+v", checks)
+	}
+}
+
+func TestCheckInstallableProvides(t *testing.T) {
+	fixtures := []*fixture{
+		{Category: "app", Name: "foo", Version: "1.0", Compression: "zst",
+			Requires: []*anise_pkg.DefaultPackage{
+				{Category: "virtual", Name: "lib", Version: ">=0"},
+			},
+			InTree: true, WithMeta: true, WithTarball: true},
+		{Category: "dev", Name: "impl", Version: "1.0", Compression: "zst",
+			Provides: []*anise_pkg.DefaultPackage{
+				{Category: "virtual", Name: "lib", Version: ">=0"},
+			},
+			InTree: true, WithMeta: true, WithTarball: true},
+		// The provider of virtual/ssl is missing.
+		{Category: "app", Name: "bar", Version: "1.0", Compression: "zst",
+			Requires: []*anise_pkg.DefaultPackage{
+				{Category: "virtual", Name: "ssl", Version: ">=0"},
+			},
+			InTree: true, WithMeta: true, WithTarball: true},
+	}
+	knife, _ := newFixtureKnife(t, specs.NewAniseRDConfig(), fixtures)
+
+	foo, _ := ParsePackageStr("app/foo")
+	bar, _ := ParsePackageStr("app/bar")
+	checks, err := knife.CheckInstallable(context.Background(),
+		[]*anise_pkg.DefaultPackage{foo, bar}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checks) != 2 {
+		t.Fatalf("Expected 2 checks, got %d", len(checks))
+	}
+	if !checks[0].Installable || len(checks[0].Missing) != 0 {
+		t.Errorf("Expected app/foo installable with dev/impl, got %+v", checks[0])
+	}
+	if checks[1].Installable || len(checks[1].Missing) != 1 {
+		t.Errorf("Expected the missing provider of virtual/ssl, got %+v", checks[1])
+	}
+}
@@ -0,0 +1,342 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+)
+
+const (
+	JournalOpCleanFile    = "clean-file"
+	JournalOpPurgeVersion = "purge-version"
+
+	// An intent entry is written before the operation and a done
+	// or failed entry after it. An intent without result means that
+	// the process was stopped during the operation.
+	JournalStatusIntent = "intent"
+	JournalStatusDone   = "done"
+	JournalStatusFailed = "failed"
+)
+
+// JournalTree describes a tree used by the run.
+type JournalTree struct {
+	Path     string `json:"path"`
+	Revision string `json:"revision,omitempty"`
+}
+
+// JournalRun contains the information about the run
+// that are stored in every entry of the journal.
+type JournalRun struct {
+	RunID     string        `json:"run_id"`
+	User      string        `json:"user"`
+	Host      string        `json:"host"`
+	Command   string        `json:"command"`
+	Backend   string        `json:"backend"`
+	Path      string        `json:"path,omitempty"`
+	SpecsHash string        `json:"specs_hash,omitempty"`
+	Trees     []JournalTree `json:"trees,omitempty"`
+	// The trees with the compiler definitions used to sort the packages.
+	BuildTrees []JournalTree `json:"build_trees,omitempty"`
+}
+
+// JournalEntry describes a write operation done on the repository.
+type JournalEntry struct {
+	JournalRun
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	Status    string    `json:"status,omitempty"`
+	File      string    `json:"file"`
+	VersionID string    `json:"version_id,omitempty"`
+	Package   string    `json:"package,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	Size      int64     `json:"size,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// Journal is an append-only JSONL file where every write
+// operation on the repository is recorded.
+type Journal struct {
+	File string
+	Run  JournalRun
+
+	mutex sync.Mutex
+	file  *os.File
+}
+
+func NewJournal(file string, run JournalRun) *Journal {
+	return &Journal{
+		File: file,
+		Run:  run,
+	}
+}
+
+// NewJournalRun returns the information of the current run. The
+// specs file is hashed and the revision of every runtime and build tree
+// is recorded.
+func NewJournalRun(command, backend, path, specsFile string,
+	trees, buildTrees []*TreeSource) (*JournalRun, error) {
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	ans := &JournalRun{
+		RunID:   hex.EncodeToString(id),
+		User:    os.Getenv("USER"),
+		Command: command,
+		Backend: backend,
+		Path:    path,
+		Trees:   newJournalTrees(trees),
+	}
+	if len(buildTrees) > 0 {
+		ans.BuildTrees = newJournalTrees(buildTrees)
+	}
+
+	if u, err := user.Current(); err == nil {
+		ans.User = u.Username
+	}
+	ans.Host, _ = os.Hostname()
+
+	if specsFile != "" {
+		data, err := os.ReadFile(specsFile)
+		if err != nil {
+			return nil, err
+		}
+		ans.SpecsHash = fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+	}
+
+	return ans, nil
+}
+
+func newJournalTrees(trees []*TreeSource) []JournalTree {
+	ans := []JournalTree{}
+	for _, t := range trees {
+		ans = append(ans, JournalTree{
+			Path:     t.Source,
+			Revision: t.Revision,
+		})
+	}
+	return ans
+}
+
+// GetGitRevision returns the commit of the HEAD of the git
+// repository that contains the path or an empty string.
+func GetGitRevision(path string) string {
+	repo, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{
+		DetectDotGit: true,
+	})
+	if err != nil {
+		return ""
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return ""
+	}
+
+	return head.Hash().String()
+}
+
+// Check verifies that the journal file is writable.
+func (j *Journal) Check() error {
+	if err := os.MkdirAll(filepath.Dir(j.File), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(j.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// Record appends an entry to the journal. The entry is written and
+// synced before the return to survive a crash of the process.
+func (j *Journal) Record(e *JournalEntry) error {
+	return j.RecordAll([]*JournalEntry{e})
+}
+
+// RecordAll appends the entries to the journal with a single sync.
+// The journal file is opened on the first write and it's kept open
+// until Close is called.
+func (j *Journal) RecordAll(entries []*JournalEntry) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	data := []byte{}
+	for _, e := range entries {
+		e.JournalRun = j.Run
+		if e.Time.IsZero() {
+			e.Time = time.Now().UTC()
+		}
+
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	if j.file == nil {
+		if err := os.MkdirAll(filepath.Dir(j.File), 0755); err != nil {
+			return err
+		}
+
+		f, err := os.OpenFile(j.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		j.file = f
+	}
+
+	if _, err := j.file.Write(data); err != nil {
+		return err
+	}
+
+	return j.file.Sync()
+}
+
+// Close closes the journal file if it's open.
+func (j *Journal) Close() error {
+	if j == nil {
+		return nil
+	}
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Close()
+	j.file = nil
+	return err
+}
+
+// JournalFilter defines the entries to return from the journal.
+// The empty fields are ignored.
+type JournalFilter struct {
+	Package string
+	File    string
+	RunID   string
+	Since   time.Time
+	Until   time.Time
+
+	compiled     bool
+	packageRegex *regexp.Regexp
+	fileRegex    *regexp.Regexp
+}
+
+// Compile compiles the regexes of the filter. It's called by Match
+// if the filter isn't already compiled.
+func (f *JournalFilter) Compile() error {
+	var err error
+
+	if f.Package != "" {
+		f.packageRegex, err = regexp.Compile(f.Package)
+		if err != nil {
+			return err
+		}
+	}
+	if f.File != "" {
+		f.fileRegex, err = regexp.Compile(f.File)
+		if err != nil {
+			return err
+		}
+	}
+
+	f.compiled = true
+	return nil
+}
+
+func (f *JournalFilter) Match(e *JournalEntry) (bool, error) {
+	if !f.compiled {
+		if err := f.Compile(); err != nil {
+			return false, err
+		}
+	}
+
+	if f.RunID != "" && f.RunID != e.RunID {
+		return false, nil
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false, nil
+	}
+	if !f.Until.IsZero() && e.Time.After(f.Until) {
+		return false, nil
+	}
+
+	if f.packageRegex != nil && !f.packageRegex.MatchString(e.Package) {
+		return false, nil
+	}
+	if f.fileRegex != nil && !f.fileRegex.MatchString(e.File) {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// ReadJournal returns the entries of the journal file that
+// match the filter.
+func ReadJournal(file string, filter *JournalFilter) ([]*JournalEntry, error) {
+	ans := []*JournalEntry{}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return ans, err
+	}
+	defer f.Close()
+
+	if filter != nil {
+		if err := filter.Compile(); err != nil {
+			return ans, err
+		}
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		e := &JournalEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), e); err != nil {
+			return ans, errors.New(fmt.Sprintf(
+				"Error on parse journal line %d: %s", line, err.Error()))
+		}
+
+		if filter != nil {
+			match, err := filter.Match(e)
+			if err != nil {
+				return ans, err
+			}
+			if !match {
+				continue
+			}
+		}
+
+		ans = append(ans, e)
+	}
+
+	return ans, scanner.Err()
+}
@@ -0,0 +1,135 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestCleanerJournal(t *testing.T) {
+	cleaner, b := newFixtureCleaner(t, cleanerFixtures, false)
+	cleaner.Force = true
+	b.Errors[cleanerFixtures[2].Tarball()] = errors.New("permission denied")
+
+	specsFile := filepath.Join(t.TempDir(), "specs.yaml")
+	if err := os.WriteFile(specsFile, []byte("cleaner: {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	trees := []*TreeSource{{Source: t.TempDir(), Kind: TreeKindLocal}}
+	buildTrees := []*TreeSource{{
+		Source: "repository:compilertree.tar.zst", Kind: TreeKindTarball,
+		Revision: "sha256:1234",
+	}}
+	run, err := NewJournalRun("clean", "memory", "", specsFile, trees, buildTrees)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.RunID == "" || run.SpecsHash == "" || len(run.Trees) != 1 ||
+		len(run.BuildTrees) != 1 || run.BuildTrees[0].Revision != "sha256:1234" {
+		t.Fatalf("Unexpected run %+v", run)
+	}
+
+	file := filepath.Join(t.TempDir(), "state", "journal.jsonl")
+	cleaner.Journal = NewJournal(file, *run)
+
+	if err := cleaner.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ReadJournal(file, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 6 {
+		t.Fatalf("Expected 6 entries, got %d", len(entries))
+	}
+
+	// Every removal has an intent entry before the result.
+	mStatus := make(map[string][]string, 0)
+	for _, e := range entries {
+		if e.RunID != run.RunID || e.Operation != JournalOpCleanFile ||
+			e.Command != "clean" || e.Reason == "" {
+			t.Fatalf("Unexpected entry %+v", e)
+		}
+		mStatus[e.File] = append(mStatus[e.File], e.Status)
+		if e.File == cleanerFixtures[2].Tarball() && e.Status != JournalStatusIntent &&
+			(e.Error == "" || e.Status != JournalStatusFailed) {
+			t.Fatalf("Expected error on entry %+v", e)
+		}
+	}
+	for f, status := range mStatus {
+		if len(status) != 2 || status[0] != JournalStatusIntent ||
+			(status[1] != JournalStatusDone && status[1] != JournalStatusFailed) {
+			t.Fatalf("Unexpected status of %s: %v", f, status)
+		}
+	}
+
+	entries, err = ReadJournal(file, &JournalFilter{Package: "^app/foo-1.0$"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("Expected 4 entries of app/foo-1.0, got %d", len(entries))
+	}
+
+	if _, err = ReadJournal(file, &JournalFilter{File: "("}); err == nil {
+		t.Fatal("Expected error with an invalid regex")
+	}
+
+	entries, err = ReadJournal(file, &JournalFilter{
+		Since: time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("Expected no entries in the future, got %d", len(entries))
+	}
+}
+
+func TestCleanerDryRunWithoutJournal(t *testing.T) {
+	cleaner, _ := newFixtureCleaner(t, cleanerFixtures, true)
+	file := filepath.Join(t.TempDir(), "journal.jsonl")
+	cleaner.Journal = NewJournal(file, JournalRun{RunID: "test"})
+
+	if err := cleaner.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Fatal("Expected no journal on dry-run")
+	}
+}
+
+func TestCleanerJournalIntents(t *testing.T) {
+	cleaner, b := newFixtureCleaner(t, cleanerFixtures, false)
+	cleaner.Force = true
+
+	file := filepath.Join(t.TempDir(), "journal.jsonl")
+	cleaner.Journal = NewJournal(file, JournalRun{RunID: "test"})
+	defer cleaner.Journal.Close()
+
+	// The intents are recorded before the removal.
+	b.OnClean = func(f string) {
+		entries, err := ReadJournal(file, &JournalFilter{File: "^" + regexp.QuoteMeta(f) + "$"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 || entries[0].Status != JournalStatusIntent {
+			t.Fatalf("Expected intent before the removal of %s, got %v", f, entries)
+		}
+	}
+
+	if err := cleaner.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
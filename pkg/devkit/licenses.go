@@ -0,0 +1,215 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	anise_pkg "github.com/geaaru/luet/pkg/package"
+)
+
+// LicensePackages contains the packages released under a license.
+type LicensePackages struct {
+	License  string   `json:"license" yaml:"license"`
+	Packages []string `json:"packages" yaml:"packages"`
+}
+
+// LicenseViolation describes a package with a license not admitted.
+type LicenseViolation struct {
+	Package string `json:"package" yaml:"package"`
+	License string `json:"license" yaml:"license"`
+	Reason  string `json:"reason" yaml:"reason"`
+}
+
+// LicensesInventory contains the licenses of the artifacts of the
+// repository.
+type LicensesInventory struct {
+	Licenses   []LicensePackages  `json:"licenses" yaml:"licenses"`
+	Unlicensed []string           `json:"unlicensed" yaml:"unlicensed"`
+	Violations []LicenseViolation `json:"violations" yaml:"violations"`
+}
+
+// licenseNode is a node of a license expression. A leaf has the
+// license name, the other nodes are satisfied by all children or
+// by one of the children when Or is true.
+type licenseNode struct {
+	License  string
+	Or       bool
+	Children []*licenseNode
+}
+
+// HasOr returns true if the expression contains a choice between licenses.
+func (n *licenseNode) HasOr() bool {
+	if n.Or && len(n.Children) > 1 {
+		return true
+	}
+	for _, c := range n.Children {
+		if c.HasOr() {
+			return true
+		}
+	}
+	return false
+}
+
+// parseLicense parses a license expression with the Gentoo syntax
+// (A B, || ( A B ), flag? ( A )) or the SPDX operators AND/OR.
+// The USE conditional groups are always required.
+func parseLicense(expr string) *licenseNode {
+	expr = strings.ReplaceAll(strings.ReplaceAll(expr, "(", " ( "), ")", " ) ")
+	tokens := strings.Fields(expr)
+	ans, _ := parseLicenseGroup(tokens, 0)
+	return ans
+}
+
+func parseLicenseGroup(tokens []string, pos int) (*licenseNode, int) {
+	ans := &licenseNode{Children: []*licenseNode{}}
+
+	for pos < len(tokens) {
+		t := tokens[pos]
+		pos++
+
+		switch {
+		case t == ")":
+			return ans, pos
+		case t == "(":
+			var n *licenseNode
+			n, pos = parseLicenseGroup(tokens, pos)
+			ans.Children = append(ans.Children, n)
+		case t == "||" || strings.HasSuffix(t, "?"):
+			if pos < len(tokens) && tokens[pos] == "(" {
+				var n *licenseNode
+				n, pos = parseLicenseGroup(tokens, pos+1)
+				n.Or = t == "||"
+				ans.Children = append(ans.Children, n)
+			}
+		case t == "OR" || t == "or":
+			ans.Or = true
+		case t == "AND" || t == "and" || t == "WITH" || t == "with":
+		default:
+			ans.Children = append(ans.Children, &licenseNode{License: t})
+		}
+	}
+
+	return ans, pos
+}
+
+// Licenses returns the names of the licenses of the expression.
+func (n *licenseNode) Licenses() []string {
+	if n.License != "" {
+		return []string{n.License}
+	}
+	ans := []string{}
+	for _, c := range n.Children {
+		ans = append(ans, c.Licenses()...)
+	}
+	return ans
+}
+
+// IsSatisfied returns true if the expression is satisfied with
+// the admitted licenses.
+func (n *licenseNode) IsSatisfied(admitted func(string) bool) bool {
+	if n.License != "" {
+		return admitted(n.License)
+	}
+	if len(n.Children) == 0 {
+		return true
+	}
+
+	for _, c := range n.Children {
+		s := c.IsSatisfied(admitted)
+		if n.Or && s {
+			return true
+		}
+		if !n.Or && !s {
+			return false
+		}
+	}
+	return !n.Or
+}
+
+func artifactLicense(p *anise_pkg.DefaultPackage) string {
+	return strings.TrimSpace(p.GetLicense())
+}
+
+// GetLicensesInventory returns the licenses of all artifacts of the
+// repository and the packages with a license not admitted by the specs.
+func (c *RepoKnife) GetLicensesInventory(ctx context.Context) (*LicensesInventory, error) {
+	conf := c.Specs.GetLicenses()
+	ans := &LicensesInventory{
+		Licenses:   []LicensePackages{},
+		Unlicensed: []string{},
+		Violations: []LicenseViolation{},
+	}
+
+	err := c.LoadMetadata(ctx, nil)
+	if err != nil {
+		return ans, err
+	}
+
+	mLicenses := make(map[string][]string, 0)
+	visited := make(map[string]bool, 0)
+
+	for _, m := range c.sortedMetas() {
+		p := artifactPackage(c.MetaMap[m])
+		if p == nil || visited[p.HumanReadableString()] {
+			continue
+		}
+		visited[p.HumanReadableString()] = true
+		pkgStr := p.HumanReadableString()
+
+		license := artifactLicense(p)
+		if license == "" {
+			ans.Unlicensed = append(ans.Unlicensed, pkgStr)
+			if conf.RequireLicense && !conf.IsExcludedPkg(p) {
+				ans.Violations = append(ans.Violations, LicenseViolation{
+					Package: pkgStr,
+					Reason:  "missing license",
+				})
+			}
+			continue
+		}
+
+		node := parseLicense(license)
+		lVisited := make(map[string]bool, 0)
+		for _, l := range node.Licenses() {
+			if !lVisited[l] {
+				mLicenses[l] = append(mLicenses[l], pkgStr)
+				lVisited[l] = true
+			}
+		}
+
+		if conf.IsExcludedPkg(p) || node.IsSatisfied(conf.IsAllowedLicense) {
+			continue
+		}
+
+		denied := []string{}
+		for _, l := range node.Licenses() {
+			if !conf.IsAllowedLicense(l) {
+				denied = append(denied, l)
+			}
+		}
+		ans.Violations = append(ans.Violations, LicenseViolation{
+			Package: pkgStr,
+			License: license,
+			Reason: fmt.Sprintf("license not admitted: %s",
+				strings.Join(denied, ", ")),
+		})
+	}
+
+	for l, pkgs := range mLicenses {
+		ans.Licenses = append(ans.Licenses, LicensePackages{
+			License:  l,
+			Packages: pkgs,
+		})
+	}
+	sort.Slice(ans.Licenses, func(i, j int) bool {
+		return ans.Licenses[i].License < ans.Licenses[j].License
+	})
+
+	return ans, nil
+}
@@ -0,0 +1,108 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	specs "github.com/macaroni-os/anise-repo-devkit/pkg/specs"
+)
+
+var licensesFixtures = []*fixture{
+	{Category: "app", Name: "foo", Version: "1.0", License: "GPL-2",
+		InTree: true, WithMeta: true, WithTarball: true},
+	{Category: "app", Name: "foo", Version: "1.1", License: "GPL-2 MIT",
+		InTree: true, WithMeta: true, WithTarball: true},
+	{Category: "app", Name: "bar", Version: "1.0", License: "|| ( BSD MIT )",
+		InTree: true, WithMeta: true, WithTarball: true},
+	{Category: "app", Name: "baz", Version: "1.0", License: "Apache-2.0 OR EULA",
+		InTree: true, WithMeta: true, WithTarball: true},
+	{Category: "app", Name: "nolic", Version: "1.0",
+		InTree: true, WithMeta: true, WithTarball: true},
+}
+
+func TestParseLicense(t *testing.T) {
+	admitted := func(l string) bool { return l != "EULA" }
+
+	for expr, expected := range map[string]bool{
+		"":                            true,
+		"GPL-2":                       true,
+		"EULA":                        false,
+		"GPL-2 EULA":                  false,
+		"|| ( GPL-2 EULA )":           true,
+		"|| ( EULA ( GPL-2 MIT ) )":   true,
+		"MIT doc? ( EULA )":           false,
+		"MIT OR EULA":                 true,
+		"MIT AND EULA":                false,
+		"MIT AND ( BSD OR EULA )":     true,
+		"GPL-2 WITH Classpath-except": true,
+	} {
+		if got := parseLicense(expr).IsSatisfied(admitted); got != expected {
+			t.Errorf("Unexpected result for %q: %v", expr, got)
+		}
+	}
+
+	got := strings.Join(parseLicense("|| ( BSD ( GPL-2 MIT ) )").Licenses(), " ")
+	if got != "BSD GPL-2 MIT" {
+		t.Errorf("Unexpected licenses: %s", got)
+	}
+}
+
+func TestGetLicensesInventory(t *testing.T) {
+	s := specs.NewAniseRDConfig()
+	knife, _ := newFixtureKnife(t, s, licensesFixtures)
+
+	inv, err := knife.GetLicensesInventory(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	licenses := []string{}
+	for _, l := range inv.Licenses {
+		licenses = append(licenses, l.License+" "+strings.Join(sortedCopy(l.Packages), ","))
+	}
+	assertFiles(t, licenses,
+		"Apache-2.0 app/baz-1.0",
+		"BSD app/bar-1.0",
+		"EULA app/baz-1.0",
+		"GPL-2 app/foo-1.0,app/foo-1.1",
+		"MIT app/bar-1.0,app/foo-1.1",
+	)
+	assertFiles(t, inv.Unlicensed, "app/nolic-1.0")
+	if len(inv.Violations) != 0 {
+		t.Errorf("Unexpected violations: %v", inv.Violations)
+	}
+
+	s.Licenses.Denied = []string{"MIT", "EULA"}
+	s.Licenses.RequireLicense = true
+	inv, err = knife.GetLicensesInventory(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	violations := []string{}
+	for _, v := range inv.Violations {
+		violations = append(violations, v.Package+" "+v.Reason)
+	}
+	assertFiles(t, violations,
+		"app/foo-1.1 license not admitted: MIT",
+		"app/nolic-1.0 missing license",
+	)
+
+	s.Licenses = specs.AniseRDCLicenses{
+		Allowed:     []string{"GPL-2", "Apache-2.0"},
+		ExcludePkgs: []specs.AnisePackage{{Category: "app", Name: "foo", Version: ">=1.1"}},
+	}
+	inv, err = knife.GetLicensesInventory(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	violations = []string{}
+	for _, v := range inv.Violations {
+		violations = append(violations, v.Package+" "+v.Reason)
+	}
+	assertFiles(t, violations, "app/bar-1.0 license not admitted: BSD, MIT")
+}
@@ -5,6 +5,7 @@ See AUTHORS and LICENSE for the license details and contributors.
 package devkit
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -36,10 +37,10 @@ func NewRepoList(s *specs.AniseRDConfig,
 	return ans, nil
 }
 
-func (c *RepoList) ListPkgsAvailable() ([]*anise_pkg.DefaultPackage, error) {
+func (c *RepoList) ListPkgsAvailable(ctx context.Context) ([]*anise_pkg.DefaultPackage, error) {
 	ans := []*anise_pkg.DefaultPackage{}
 
-	err := c.RepoKnife.Analyze()
+	err := c.RepoKnife.Analyze(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -51,11 +52,11 @@ func (c *RepoList) ListPkgsAvailable() ([]*anise_pkg.DefaultPackage, error) {
 	return ans, nil
 }
 
-func (c *RepoList) ListPkgsMissing() ([]*anise_pkg.DefaultPackage, error) {
+func (c *RepoList) ListPkgsMissing(ctx context.Context) ([]*anise_pkg.DefaultPackage, error) {
 	ans := []*anise_pkg.DefaultPackage{}
 	mPkgs := make(map[string]bool, 0)
 
-	err := c.RepoKnife.Analyze()
+	err := c.RepoKnife.Analyze(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -84,11 +85,14 @@ func (c *RepoList) ListPkgsMissing() ([]*anise_pkg.DefaultPackage, error) {
 	return ans, nil
 }
 
-func (c *RepoList) ListPkgsMissingByDeps(treePaths []string, withResolve bool) ([]*anise_pkg.DefaultPackage, error) {
+// ListPkgsMissingByDeps returns the packages of the runtime trees missing
+// in the repository sorted by build order. The order is computed with the
+// build time dependencies of the compiler definitions of the build trees.
+func (c *RepoList) ListPkgsMissingByDeps(ctx context.Context, buildTreePaths []string, withResolve bool) ([]*anise_pkg.DefaultPackage, error) {
 	ans := []*anise_pkg.DefaultPackage{}
 	reciperBuild := anise_tree.NewCompilerRecipe(anise_pkg.NewInMemoryDatabase(false))
 
-	list, err := c.ListPkgsMissing()
+	list, err := c.ListPkgsMissing(ctx)
 	if err != nil {
 		return list, err
 	}
@@ -96,7 +100,7 @@ func (c *RepoList) ListPkgsMissingByDeps(treePaths []string, withResolve bool) (
 	pc := converter.NewPortageConverter("", "repoman")
 
 	// Load ReciperBuildtime
-	for _, t := range treePaths {
+	for _, t := range buildTreePaths {
 		if c.Verbose {
 			InfoC(fmt.Sprintf(":evergreen_tree: Loading tree %s...", t))
 		} else {
@@ -110,7 +114,7 @@ func (c *RepoList) ListPkgsMissingByDeps(treePaths []string, withResolve bool) (
 
 	// Using local load of the three to reduce log verbosity.
 	pc.ReciperBuild = reciperBuild
-	pc.TreePaths = treePaths
+	pc.TreePaths = buildTreePaths
 
 	// Create a map of the packages
 	mMissings := make(map[string]*anise_pkg.DefaultPackage, 0)
@@ -120,12 +124,13 @@ func (c *RepoList) ListPkgsMissingByDeps(treePaths []string, withResolve bool) (
 		r, err := reciperBuild.GetDatabase().FindPackage(list[idx])
 		if err != nil {
 			return ans, errors.New(
-				fmt.Sprintf("Error on resolve package %s", p.HumanReadableString()),
+				fmt.Sprintf("Error on resolve package %s: not available in the build trees",
+					p.HumanReadableString()),
 			)
 		}
 
-		// TODO: R is with broken requires!!!
-		//mMissings[p.HumanReadableString()] = r.(*anise_pkg.DefaultPackage)
+		// The dependencies graph uses the build time requires of the
+		// compiler definitions. The runtime package is returned.
 		mMissings[p.HumanReadableString()] = list[idx]
 		pList = append(pList, r)
 	}
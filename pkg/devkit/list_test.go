@@ -0,0 +1,67 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"context"
+	"testing"
+
+	specs "github.com/macaroni-os/anise-repo-devkit/pkg/specs"
+
+	anise_pkg "github.com/geaaru/luet/pkg/package"
+)
+
+func TestListPkgsMissingByDeps(t *testing.T) {
+	s := specs.NewAniseRDConfig()
+
+	// The runtime tree has a runtime dependency of lib/bar on app/foo
+	// and it hasn't the package available only in the build tree.
+	runtimeFixtures := []*fixture{
+		{Category: "app", Name: "foo", Version: "1.0", InTree: true},
+		{Category: "lib", Name: "bar", Version: "1.0", InTree: true,
+			Requires: []*anise_pkg.DefaultPackage{
+				{Category: "app", Name: "foo", Version: ">=1.0"},
+			}},
+		{Category: "sys", Name: "base", Version: "1.0", InTree: true,
+			WithMeta: true, WithTarball: true},
+	}
+	// The compiler definitions require lib/bar to build app/foo.
+	buildFixtures := []*fixture{
+		{Category: "app", Name: "foo", Version: "1.0", InTree: true,
+			Requires: []*anise_pkg.DefaultPackage{
+				{Category: "lib", Name: "bar", Version: ">=1.0"},
+			}},
+		{Category: "lib", Name: "bar", Version: "1.0", InTree: true},
+		{Category: "sys", Name: "base", Version: "1.0", InTree: true},
+		{Category: "dev", Name: "tool", Version: "1.0", InTree: true},
+	}
+
+	b := newFixtureBackend(t, s, runtimeFixtures)
+	list := &RepoList{RepoKnife: NewRepoKnifeWithBackend(s, b)}
+	if err := list.LoadTrees([]string{newFixtureTree(t, runtimeFixtures)}); err != nil {
+		t.Fatal(err)
+	}
+
+	pkgs, err := list.ListPkgsMissingByDeps(context.Background(),
+		[]string{newFixtureTree(t, buildFixtures)}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order := []string{}
+	for _, p := range pkgs {
+		order = append(order, p.HumanReadableString())
+	}
+	if len(order) != 2 || order[0] != "lib/bar-1.0" || order[1] != "app/foo-1.0" {
+		t.Fatalf("Unexpected build order %v", order)
+	}
+
+	// The missing packages must be available in the build trees.
+	_, err = list.ListPkgsMissingByDeps(context.Background(),
+		[]string{newFixtureTree(t, buildFixtures[1:])}, false)
+	if err == nil {
+		t.Error("Expected error with a package missing in the build trees")
+	}
+}
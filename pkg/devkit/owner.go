@@ -0,0 +1,96 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// FileOwner describes a file shipped by an artifact.
+type FileOwner struct {
+	Package  string `json:"package" yaml:"package"`
+	MetaFile string `json:"metafile" yaml:"metafile"`
+	File     string `json:"file" yaml:"file"`
+}
+
+// ownerMatcher returns the function to match the files of the artifacts
+// with a path or a glob. The pattern without slashes is matched with
+// the base name of the files.
+func ownerMatcher(pattern string) (func(string) bool, error) {
+	pattern = strings.TrimPrefix(pattern, "/")
+	if pattern == "" {
+		return nil, errors.New("Invalid empty path")
+	}
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, errors.New(fmt.Sprintf("Invalid pattern %s: %s",
+			pattern, err.Error()))
+	}
+
+	baseOnly := !strings.Contains(pattern, "/")
+
+	return func(f string) bool {
+		f = strings.TrimPrefix(f, "/")
+		if baseOnly {
+			f = path.Base(f)
+		}
+		match, _ := path.Match(pattern, f)
+		return match
+	}, nil
+}
+
+// SearchOwners returns the files of the artifacts that match one of the
+// paths or globs. The metadata of all artifacts are loaded.
+func (c *RepoKnife) SearchOwners(ctx context.Context, patterns []string) ([]FileOwner, error) {
+	ans := []FileOwner{}
+
+	matchers := []func(string) bool{}
+	for _, p := range patterns {
+		m, err := ownerMatcher(p)
+		if err != nil {
+			return ans, err
+		}
+		matchers = append(matchers, m)
+	}
+
+	err := c.LoadMetadata(ctx, nil)
+	if err != nil {
+		return ans, err
+	}
+
+	metas := []string{}
+	for m := range c.MetaMap {
+		metas = append(metas, m)
+	}
+	sort.Strings(metas)
+
+	for _, m := range metas {
+		art := c.MetaMap[m]
+		p := artifactPackage(art)
+		if p == nil {
+			continue
+		}
+
+		for _, f := range art.Files {
+			for _, match := range matchers {
+				if match(f) {
+					ans = append(ans, FileOwner{
+						Package:  p.HumanReadableString(),
+						MetaFile: m,
+						File:     "/" + strings.TrimPrefix(f, "/"),
+					})
+					break
+				}
+			}
+		}
+	}
+
+	return ans, nil
+}
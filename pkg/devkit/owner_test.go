@@ -0,0 +1,113 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	specs "github.com/macaroni-os/anise-repo-devkit/pkg/specs"
+)
+
+var ownerFixtures = []*fixture{
+	{Category: "lib", Name: "foo", Version: "1.0", Compression: "zst",
+		Files: []string{"usr/lib/libfoo.so", "usr/lib/libfoo.so.1",
+			"usr/share/doc/foo/README"},
+		InTree: true, WithMeta: true, WithTarball: true},
+	{Category: "app", Name: "bar", Version: "1.0", Compression: "zst",
+		Files:  []string{"usr/bin/bar", "usr/share/doc/bar/README"},
+		InTree: true, WithMeta: true, WithTarball: true},
+}
+
+func TestSearchOwners(t *testing.T) {
+	knife, _ := newFixtureKnife(t, specs.NewAniseRDConfig(), ownerFixtures)
+
+	for _, tc := range []struct {
+		patterns []string
+		expected []string
+	}{
+		{[]string{"/usr/lib/libfoo.so"}, []string{"/usr/lib/libfoo.so"}},
+		{[]string{"libfoo.so*"}, []string{"/usr/lib/libfoo.so", "/usr/lib/libfoo.so.1"}},
+		{[]string{"/usr/share/doc/*/README"},
+			[]string{"/usr/share/doc/bar/README", "/usr/share/doc/foo/README"}},
+		{[]string{"/usr/bin/*", "libfoo.so"}, []string{"/usr/bin/bar", "/usr/lib/libfoo.so"}},
+		{[]string{"/usr/lib/libbar.so"}, []string{}},
+	} {
+		owners, err := knife.SearchOwners(context.Background(), tc.patterns)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		files := []string{}
+		for _, o := range owners {
+			files = append(files, o.File)
+			if o.Package == "" {
+				t.Fatalf("Owner without package %+v", o)
+			}
+		}
+		assertFiles(t, files, tc.expected...)
+	}
+
+	if _, err := knife.SearchOwners(context.Background(), []string{"[foo"}); err == nil {
+		t.Fatal("Expected error on invalid pattern")
+	}
+}
+
+func TestMetadataCache(t *testing.T) {
+	knife, b := newFixtureKnife(t, specs.NewAniseRDConfig(), ownerFixtures)
+	knife.Cache = NewMetadataCache(t.TempDir(), "memory")
+
+	for i := 0; i < 2; i++ {
+		owners, err := knife.SearchOwners(context.Background(), []string{"/usr/bin/bar"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(owners) != 1 || owners[0].Package != "app/bar-1.0" {
+			t.Fatalf("Unexpected owners %+v", owners)
+		}
+	}
+
+	// The second search uses the cache.
+	if calls := b.GetCalls("GetMetadata"); len(calls) != 2 {
+		t.Fatalf("Expected 2 GetMetadata calls, got %d", len(calls))
+	}
+
+	// A modified metadata file is downloaded again and the old
+	// entry is pruned.
+	b.AddFile(ownerFixtures[0].MetaFile(), append(b.Files[ownerFixtures[0].MetaFile()], '\n'))
+	if _, err := knife.SearchOwners(context.Background(), []string{"/usr/bin/bar"}); err != nil {
+		t.Fatal(err)
+	}
+	if calls := b.GetCalls("GetMetadata"); len(calls) != 3 {
+		t.Fatalf("Expected 3 GetMetadata calls, got %d", len(calls))
+	}
+
+	entries, err := os.ReadDir(knife.Cache.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 cache entries, got %d", len(entries))
+	}
+}
+
+func TestMetadataCacheWithoutFilesInfo(t *testing.T) {
+	s := specs.NewAniseRDConfig()
+	b := newFixtureBackend(t, s, ownerFixtures)
+
+	// A backend without the files information.
+	knife := NewRepoKnifeWithBackend(s, struct{ specs.RepoBackendHandler }{b})
+	knife.SetCache(NewMetadataCache(t.TempDir(), "memory"))
+	if knife.Cache != nil {
+		t.Fatal("Expected the cache disabled without files information")
+	}
+
+	knife = NewRepoKnifeWithBackend(s, b)
+	knife.SetCache(NewMetadataCache(t.TempDir(), "memory"))
+	if knife.Cache == nil {
+		t.Fatal("Expected the cache enabled")
+	}
+}
@@ -0,0 +1,158 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	sprig "github.com/Masterminds/sprig/v3"
+	units "github.com/docker/go-units"
+	anise_pkg "github.com/geaaru/luet/pkg/package"
+)
+
+// PkgView is the view of a package used by the pkgs command outputs
+// and templates. The artifact fields are empty if the package isn't
+// available in the repository.
+type PkgView struct {
+	Package     string                 `json:"package" yaml:"package"`
+	Category    string                 `json:"category" yaml:"category"`
+	Name        string                 `json:"name" yaml:"name"`
+	Version     string                 `json:"version" yaml:"version"`
+	Description string                 `json:"description,omitempty" yaml:"description,omitempty"`
+	License     string                 `json:"license,omitempty" yaml:"license,omitempty"`
+	Uri         []string               `json:"uri,omitempty" yaml:"uri,omitempty"`
+	Labels      map[string]string      `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Annotations map[string]interface{} `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+	Hidden      bool                   `json:"hidden" yaml:"hidden"`
+	Requires    []string               `json:"requires,omitempty" yaml:"requires,omitempty"`
+	Conflicts   []string               `json:"conflicts,omitempty" yaml:"conflicts,omitempty"`
+
+	MetaFile        string `json:"metafile,omitempty" yaml:"metafile,omitempty"`
+	Path            string `json:"path,omitempty" yaml:"path,omitempty"`
+	Size            int64  `json:"size,omitempty" yaml:"size,omitempty"`
+	Checksum        string `json:"checksum,omitempty" yaml:"checksum,omitempty"`
+	CompressionType string `json:"compression_type,omitempty" yaml:"compression_type,omitempty"`
+	BuildTimestamp  string `json:"build_timestamp,omitempty" yaml:"build_timestamp,omitempty"`
+}
+
+// NewPkgsView returns the views of the packages with the artifact
+// fields of the loaded metadata.
+func (c *RepoKnife) NewPkgsView(list []*anise_pkg.DefaultPackage) []*PkgView {
+	ans := []*PkgView{}
+
+	// Index the artifacts by package
+	metas := make(map[string]string, 0)
+	for _, m := range c.sortedMetas() {
+		p := artifactPackage(c.MetaMap[m])
+		if p == nil {
+			continue
+		}
+		if _, ok := metas[p.HumanReadableString()]; !ok {
+			metas[p.HumanReadableString()] = m
+		}
+	}
+
+	for _, p := range list {
+		v := &PkgView{
+			Package:     p.HumanReadableString(),
+			Category:    p.GetCategory(),
+			Name:        p.GetName(),
+			Version:     p.GetVersion(),
+			Description: p.GetDescription(),
+			License:     p.GetLicense(),
+			Uri:         p.Uri,
+			Labels:      p.Labels,
+			Annotations: p.Annotations,
+			Hidden:      p.Hidden,
+			Requires:    pkgsStrings(p.GetRequires()),
+			Conflicts:   pkgsStrings(p.GetConflicts()),
+		}
+
+		if m, ok := metas[p.HumanReadableString()]; ok {
+			art := c.MetaMap[m]
+			v.MetaFile = m
+			v.Path = filepath.Base(art.Path)
+			v.Size = c.FilesSize[v.Path]
+			v.Checksum = art.Checksums["sha256"]
+			v.CompressionType = string(art.CompressionType)
+			v.BuildTimestamp = artifactPackage(art).GetBuildTimestamp()
+		}
+
+		ans = append(ans, v)
+	}
+
+	return ans
+}
+
+// WritePkgsTable writes the views as a table.
+func WritePkgsTable(w io.Writer, views []*PkgView) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "PACKAGE\tPATH\tSIZE\tBUILD TIMESTAMP")
+	for _, v := range views {
+		size := ""
+		if v.Size > 0 {
+			size = units.BytesSize(float64(v.Size))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", v.Package, v.Path, size, v.BuildTimestamp)
+	}
+	return tw.Flush()
+}
+
+// WritePkgsCSV writes the views in CSV format with a header.
+func WritePkgsCSV(w io.Writer, views []*PkgView) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write([]string{
+		"category", "name", "version", "license", "path", "size",
+		"checksum", "build_timestamp",
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, v := range views {
+		err = cw.Write([]string{
+			v.Category, v.Name, v.Version, v.License, v.Path,
+			strconv.FormatInt(v.Size, 10), v.Checksum, v.BuildTimestamp,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// WritePkgsTemplate executes the Go template with the sprig functions
+// for every view. A newline is added after every package if the
+// template doesn't end with a newline.
+func WritePkgsTemplate(w io.Writer, views []*PkgView, tmpl string) error {
+	t, err := template.New("pkgs").Funcs(sprig.TxtFuncMap()).Parse(tmpl)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Invalid template: %s", err.Error()))
+	}
+
+	for _, v := range views {
+		if err = t.Execute(w, v); err != nil {
+			return errors.New(fmt.Sprintf("Error on execute template for %s: %s",
+				v.Package, err.Error()))
+		}
+		if !strings.HasSuffix(tmpl, "\n") {
+			if _, err = io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
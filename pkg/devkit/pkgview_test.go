@@ -0,0 +1,76 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	specs "github.com/macaroni-os/anise-repo-devkit/pkg/specs"
+
+	anise_pkg "github.com/geaaru/luet/pkg/package"
+)
+
+var pkgViewFixtures = []*fixture{
+	{Category: "app", Name: "foo", Version: "1.0", Compression: "zst",
+		License: "MIT", BuildTimestamp: "1735732800",
+		InTree: true, WithMeta: true, WithTarball: true},
+	{Category: "app", Name: "bar", Version: "2.0",
+		InTree: true},
+}
+
+func TestPkgsView(t *testing.T) {
+	s := specs.NewAniseRDConfig()
+	knife, b := newFixtureKnife(t, s, pkgViewFixtures)
+	b.AddFile(pkgViewFixtures[0].Tarball(), make([]byte, 2048))
+
+	if err := knife.LoadMetadata(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	views := knife.NewPkgsView([]*anise_pkg.DefaultPackage{
+		pkgViewFixtures[0].Package(),
+		pkgViewFixtures[1].Package(),
+	})
+	if len(views) != 2 {
+		t.Fatalf("Unexpected views: %v", views)
+	}
+
+	foo := views[0]
+	if foo.Package != "app/foo-1.0" || foo.Path != pkgViewFixtures[0].Tarball() ||
+		foo.Size != 2048 || foo.License != "MIT" || foo.CompressionType != "zstd" ||
+		foo.BuildTimestamp != "1735732800" {
+		t.Errorf("Unexpected view: %v", foo)
+	}
+	if views[1].Path != "" || views[1].Size != 0 {
+		t.Errorf("Unexpected view of a missing package: %v", views[1])
+	}
+
+	var buf bytes.Buffer
+	err := WritePkgsTemplate(&buf, views, `{{ .Package }} {{ .Size }} {{ .Path | default "-" | upper }}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "app/foo-1.0 2048 FOO-APP-1.0.PACKAGE.TAR.ZST\napp/bar-2.0 0 -\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected template output:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err = WritePkgsCSV(&buf, views); err != nil {
+		t.Fatal(err)
+	}
+	expected = "category,name,version,license,path,size,checksum,build_timestamp\n" +
+		"app,foo,1.0,MIT,foo-app-1.0.package.tar.zst,2048,,1735732800\n" +
+		"app,bar,2.0,,,0,,\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected CSV output:\n%s", buf.String())
+	}
+
+	if err = WritePkgsTemplate(&buf, views, "{{ .Package "); err == nil {
+		t.Error("Expected error with an invalid template")
+	}
+}
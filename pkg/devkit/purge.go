@@ -0,0 +1,190 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	specs "github.com/macaroni-os/anise-repo-devkit/pkg/specs"
+
+	units "github.com/docker/go-units"
+	. "github.com/geaaru/luet/pkg/logger"
+)
+
+// The reason of the versions purged by RepoPurger.
+const ReasonNoncurrentVersion RemoveReason = "noncurrent-version"
+
+// RepoPurger removes the noncurrent versions of the objects
+// of a versioned backend older than the retention window.
+type RepoPurger struct {
+	*RepoKnife
+	DryRun    bool
+	Retention time.Duration
+
+	Versions2Remove []specs.ObjectVersion
+	RemovedVersions int
+	ReclaimedSize   int64
+	// The result of every version processed.
+	Results []specs.CleanResult
+}
+
+func NewRepoPurger(s *specs.AniseRDConfig,
+	backend, path string, opts map[string]string,
+	retention time.Duration, dryRun bool) (*RepoPurger, error) {
+
+	knife, err := NewRepoKnife(s, backend, path, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ans := &RepoPurger{
+		RepoKnife: knife,
+		DryRun:    dryRun,
+		Retention: retention,
+	}
+
+	return ans, nil
+}
+
+func NewRepoPurgerWithBackend(s *specs.AniseRDConfig,
+	handler specs.RepoBackendHandler,
+	retention time.Duration, dryRun bool) *RepoPurger {
+	return &RepoPurger{
+		RepoKnife: NewRepoKnifeWithBackend(s, handler),
+		DryRun:    dryRun,
+		Retention: retention,
+	}
+}
+
+func (c *RepoPurger) Run(ctx context.Context) error {
+	var err error
+
+	vBackend, ok := c.BackendHandler.(specs.RepoBackendVersioned)
+	if !ok {
+		return errors.New("The backend doesn't support objects versioning")
+	}
+
+	if !vBackend.IsVersioned() {
+		InfoC("The versioning is not enabled. Nothing to purge.")
+		return nil
+	}
+
+	c.RemovedVersions = 0
+	c.ReclaimedSize = 0
+	c.Results = []specs.CleanResult{}
+
+	c.Versions2Remove, err = vBackend.GetNoncurrentVersions(ctx,
+		time.Now().Add(-c.Retention))
+	if err != nil {
+		return err
+	}
+
+	if len(c.Versions2Remove) == 0 {
+		InfoC("No versions to purge.")
+		return nil
+	}
+
+	if c.DryRun {
+		for _, v := range c.Versions2Remove {
+			InfoC(fmt.Sprintf("[%s] Version %s (%s) could be purged.",
+				v.File, v.VersionID, units.BytesSize(float64(v.Size))))
+			c.ReclaimedSize += v.Size
+		}
+		return nil
+	}
+
+	for i := 0; i < len(c.Versions2Remove); i += CleanBatchSize {
+		if ctx.Err() != nil {
+			return fmt.Errorf(
+				"Purge interrupted after %d of %d versions: %w",
+				c.RemovedVersions, len(c.Versions2Remove), ctx.Err())
+		}
+
+		end := i + CleanBatchSize
+		if end > len(c.Versions2Remove) {
+			end = len(c.Versions2Remove)
+		}
+
+		intents := []*JournalEntry{}
+		for _, v := range c.Versions2Remove[i:end] {
+			intents = append(intents, newPurgeEntry(v))
+		}
+		if err := c.recordJournalIntents(intents); err != nil {
+			return err
+		}
+
+		results := vBackend.PurgeVersions(context.WithoutCancel(ctx),
+			c.Versions2Remove[i:end])
+		for idx, r := range results {
+			v := c.Versions2Remove[i+idx]
+			c.Results = append(c.Results, r)
+
+			entry := newPurgeEntry(v)
+			entry.Status = JournalStatusDone
+			if r.Error != nil {
+				entry.Status = JournalStatusFailed
+				entry.Error = r.Error.Error()
+			}
+			c.recordJournal(entry)
+			if r.Error != nil {
+				Error(fmt.Sprintf("[%s] Error on purge version %s: %s",
+					r.File, v.VersionID, r.Error.Error()))
+				continue
+			}
+			InfoC(fmt.Sprintf("[%s] Version %s purged.", r.File, v.VersionID))
+			c.RemovedVersions += r.Versions
+			c.ReclaimedSize += r.Reclaimed
+		}
+	}
+
+	return nil
+}
+
+// newPurgeEntry returns the journal entry of the purge of a version.
+func newPurgeEntry(v specs.ObjectVersion) *JournalEntry {
+	return &JournalEntry{
+		Operation: JournalOpPurgeVersion,
+		File:      v.File,
+		VersionID: v.VersionID,
+		Reason:    string(ReasonNoncurrentVersion),
+		Size:      v.Size,
+	}
+}
+
+// GetReport returns the report of the last run.
+func (c *RepoPurger) GetReport() *Report {
+	ans := NewReport("purge-noncurrent", c.DryRun)
+	ans.ProcessedFiles = len(c.Versions2Remove)
+
+	for idx, v := range c.Versions2Remove {
+		rf := ReportFile{
+			File:      v.File,
+			VersionID: v.VersionID,
+			Reason:    string(ReasonNoncurrentVersion),
+			Size:      v.Size,
+			Status:    ReportStatusRemovable,
+		}
+
+		if !c.DryRun {
+			switch {
+			case idx >= len(c.Results):
+				rf.Status = ReportStatusSkipped
+			case c.Results[idx].Error != nil:
+				rf.Status = ReportStatusFailed
+				rf.Error = c.Results[idx].Error.Error()
+			default:
+				rf.Status = ReportStatusRemoved
+				rf.Reclaimed = c.Results[idx].Reclaimed
+			}
+		}
+
+		ans.AddFile(rf)
+	}
+
+	return ans
+}
@@ -0,0 +1,159 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/macaroni-os/anise-repo-devkit/pkg/backends"
+	specs "github.com/macaroni-os/anise-repo-devkit/pkg/specs"
+)
+
+// newVersionedBackend returns a versioned backend with:
+//   - foo.tar: two versions noncurrent since 20 days and the current one
+//   - bar.tar: a version removed 10 days ago with a delete marker
+//   - baz.tar: a version noncurrent since 1 day
+func newVersionedBackend(t *testing.T) *backends.BackendMemory {
+	t.Helper()
+
+	days := func(n int) time.Time {
+		return time.Now().Add(-time.Duration(n) * 24 * time.Hour)
+	}
+
+	b := backends.NewBackendMemory(specs.NewAniseRDConfig())
+	b.Versioned = true
+	b.AddVersion("foo.tar", make([]byte, 10), days(40))
+	b.AddVersion("foo.tar", make([]byte, 20), days(30))
+	b.AddVersion("foo.tar", make([]byte, 30), days(20))
+	b.AddVersion("bar.tar", make([]byte, 5), days(30))
+	b.AddDeleteMarker("bar.tar", days(10))
+	b.AddVersion("baz.tar", make([]byte, 7), days(30))
+	b.AddVersion("baz.tar", make([]byte, 8), days(1))
+
+	return b
+}
+
+func TestPurgerRun(t *testing.T) {
+	b := newVersionedBackend(t)
+	purger := NewRepoPurgerWithBackend(specs.NewAniseRDConfig(), b,
+		7*24*time.Hour, false)
+	b.Errors["bar.tar"] = errors.New("permission denied")
+
+	file := filepath.Join(t.TempDir(), "journal.jsonl")
+	purger.Journal = NewJournal(file, JournalRun{RunID: "test"})
+
+	if err := purger.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	purger.Journal.Close()
+
+	// The versions of foo.tar older than the current and the version
+	// of bar.tar with its delete marker.
+	selected := []string{}
+	for _, v := range purger.Versions2Remove {
+		selected = append(selected, v.File+"@"+v.VersionID)
+	}
+	assertFiles(t, selected, "foo.tar@v1", "foo.tar@v2", "bar.tar@v4", "bar.tar@v5")
+
+	if purger.RemovedVersions != 2 || purger.ReclaimedSize != 30 {
+		t.Fatalf("Expected 2 versions and 30 bytes purged, got %d and %d",
+			purger.RemovedVersions, purger.ReclaimedSize)
+	}
+
+	// The current versions are never touched.
+	if v := b.GetVersions("foo.tar"); len(v) != 1 || v[0].VersionID != "v3" ||
+		len(b.Files["foo.tar"]) != 30 {
+		t.Fatalf("Unexpected versions of foo.tar %+v", v)
+	}
+	if v := b.GetVersions("baz.tar"); len(v) != 2 {
+		t.Fatalf("Unexpected versions of baz.tar %+v", v)
+	}
+
+	entries, err := ReadJournal(file, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mStatus := make(map[string][]string, 0)
+	for _, e := range entries {
+		if e.Operation != JournalOpPurgeVersion || e.VersionID == "" {
+			t.Fatalf("Unexpected entry %+v", e)
+		}
+		key := e.File + "@" + e.VersionID
+		mStatus[key] = append(mStatus[key], e.Status)
+	}
+	for _, v := range selected {
+		status := mStatus[v]
+		expected := JournalStatusDone
+		if strings.HasPrefix(v, "bar.tar@") {
+			expected = JournalStatusFailed
+		}
+		if len(status) != 2 || status[0] != JournalStatusIntent || status[1] != expected {
+			t.Fatalf("Unexpected status of %s: %v", v, status)
+		}
+	}
+
+	report := purger.GetReport()
+	if report.ProcessedFiles != 4 || len(report.Files) != 4 ||
+		report.ReclaimedSize != 30 || report.FailedFiles != 2 {
+		t.Fatalf("Unexpected report %+v", report)
+	}
+	for _, f := range report.Files {
+		expected := ReportStatusRemoved
+		if f.File == "bar.tar" {
+			expected = ReportStatusFailed
+		}
+		if f.Status != expected || f.Reason != string(ReasonNoncurrentVersion) {
+			t.Fatalf("Unexpected report file %+v", f)
+		}
+	}
+}
+
+func TestPurgerDryRun(t *testing.T) {
+	b := newVersionedBackend(t)
+	purger := NewRepoPurgerWithBackend(specs.NewAniseRDConfig(), b,
+		7*24*time.Hour, true)
+
+	if err := purger.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls := b.GetCalls("PurgeVersions"); len(calls) != 0 {
+		t.Fatalf("Expected no PurgeVersions calls on dry-run, got %v", calls)
+	}
+	if len(purger.Versions2Remove) != 4 || purger.ReclaimedSize != 35 {
+		t.Fatalf("Expected 4 versions and 35 bytes, got %d and %d",
+			len(purger.Versions2Remove), purger.ReclaimedSize)
+	}
+	for _, f := range purger.GetReport().Files {
+		if f.Status != ReportStatusRemovable {
+			t.Fatalf("Unexpected report file %+v", f)
+		}
+	}
+}
+
+func TestPurgerNotVersioned(t *testing.T) {
+	b := backends.NewBackendMemory(specs.NewAniseRDConfig())
+	b.AddFile("foo.tar", []byte{})
+	purger := NewRepoPurgerWithBackend(specs.NewAniseRDConfig(), b, 0, false)
+
+	if err := purger.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if calls := b.GetCalls("GetNoncurrentVersions"); len(calls) != 0 {
+		t.Fatalf("Expected no listing of the versions, got %v", calls)
+	}
+
+	// The backends without versioning are rejected.
+	purger = NewRepoPurgerWithBackend(specs.NewAniseRDConfig(),
+		struct{ specs.RepoBackendHandler }{b}, 0, false)
+	if err := purger.Run(context.Background()); err == nil {
+		t.Fatal("Expected error with a backend without versions")
+	}
+}
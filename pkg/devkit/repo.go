@@ -6,10 +6,13 @@ See AUTHORS and LICENSE for the license details and contributors.
 package devkit
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"time"
 
 	"github.com/macaroni-os/anise-repo-devkit/pkg/backends"
 	specs "github.com/macaroni-os/anise-repo-devkit/pkg/specs"
@@ -21,6 +24,18 @@ import (
 	tmtools "github.com/geaaru/time-master/pkg/tools"
 )
 
+// RemoveReason describes why a file is marked for removal.
+type RemoveReason string
+
+const (
+	ReasonOrphanMetadata  RemoveReason = "orphan-metadata"
+	ReasonOrphanTarball   RemoveReason = "orphan-tarball"
+	ReasonDroppedFromTree RemoveReason = "dropped-from-tree"
+	ReasonUnknownFile     RemoveReason = "unknown-file"
+	ReasonSignatureFile   RemoveReason = "signature-file"
+	ReasonChecksumFile    RemoveReason = "checksum-file"
+)
+
 type RepoKnife struct {
 	Specs          *specs.AniseRDConfig
 	BackendHandler specs.RepoBackendHandler
@@ -31,6 +46,33 @@ type RepoKnife struct {
 	Files2Remove   []string
 	Verbose        bool
 	ProcessedFiles int
+
+	// The reason of removal of every file in Files2Remove.
+	Removals map[string]RemoveReason
+	// The class of every file analyzed.
+	FilesClass map[string]FileClass
+	// The size of the files if available from the backend.
+	FilesSize map[string]int64
+	// The modification time of the files if available from the backend.
+	FilesModTime map[string]time.Time
+	// Optional cache of the metadata files. Use SetCache to enable it
+	// only with the backends that return the files information.
+	Cache *MetadataCache
+	// The duplicated files found. The value is the metafile kept.
+	Duplicates map[string]string
+
+	// Optional journal where the write operations are recorded.
+	Journal *Journal
+	// The sources of the trees loaded with LoadTreeSources.
+	Trees []*TreeSource
+	// The sources of the build trees recorded in the reports.
+	BuildTrees []*TreeSource
+
+	// The metafiles without tarball removed from MetaMap.
+	orphanMetaMap map[string]*artifact.PackageArtifact
+	// Artifacts kept or reported because still required. The key
+	// is the metafile and the value the first package that requires it.
+	RequiredArtifacts map[string]string
 }
 
 func NewRepoKnife(s *specs.AniseRDConfig,
@@ -38,13 +80,6 @@ func NewRepoKnife(s *specs.AniseRDConfig,
 	var err error
 	var handler specs.RepoBackendHandler
 
-	ans := &RepoKnife{
-		Specs:          s,
-		ReciperRuntime: anise_tree.NewInstallerRecipe(anise_pkg.NewInMemoryDatabase(false)),
-		PkgsMap:        make(map[string]string, 0),
-		MetaMap:        make(map[string]*artifact.PackageArtifact, 0),
-	}
-
 	switch backend {
 	case "local":
 		handler, err = backends.NewBackendLocal(s, path)
@@ -59,8 +94,39 @@ func NewRepoKnife(s *specs.AniseRDConfig,
 	if err != nil {
 		return nil, err
 	}
-	ans.BackendHandler = handler
-	return ans, nil
+
+	return NewRepoKnifeWithBackend(s, handler), nil
+}
+
+func NewRepoKnifeWithBackend(s *specs.AniseRDConfig,
+	handler specs.RepoBackendHandler) *RepoKnife {
+	return &RepoKnife{
+		Specs:          s,
+		BackendHandler: handler,
+		ReciperRuntime: anise_tree.NewInstallerRecipe(anise_pkg.NewInMemoryDatabase(false)),
+		PkgsMap:        make(map[string]string, 0),
+		MetaMap:        make(map[string]*artifact.PackageArtifact, 0),
+	}
+}
+
+// SetCache enables the metadata cache. The cache entries are validated
+// with the size and the modification time of the metadata files, so the
+// cache is disabled with a warning for the backends that don't return
+// them (for example mottainai).
+func (c *RepoKnife) SetCache(cache *MetadataCache) {
+	if cache == nil {
+		c.Cache = nil
+		return
+	}
+
+	if _, ok := c.BackendHandler.(specs.RepoBackendFilesInfo); !ok {
+		Warning("The backend doesn't return the size and the modification " +
+			"time of the files. The metadata cache is disabled.")
+		c.Cache = nil
+		return
+	}
+
+	c.Cache = cache
 }
 
 func (c *RepoKnife) LoadTrees(treePath []string) error {
@@ -81,45 +147,41 @@ func (c *RepoKnife) LoadTrees(treePath []string) error {
 	return nil
 }
 
-func (c *RepoKnife) Analyze() error {
+// LoadTreeSources loads the trees resolved with ResolveTrees.
+func (c *RepoKnife) LoadTreeSources(trees []*TreeSource) error {
+	c.Trees = trees
+	return c.LoadTrees(GetTreesPaths(trees))
+}
+
+// LoadMetadata retrieves the list of the files of the repository,
+// classifies them and downloads the metadata files. The files not
+// accepted by the filter are ignored. If the filter is nil all files
+// are processed.
+func (c *RepoKnife) LoadMetadata(ctx context.Context, filter func(string) bool) error {
 
 	// Reset previous values
 	c.PkgsMap = make(map[string]string, 0)
 	c.MetaMap = make(map[string]*artifact.PackageArtifact, 0)
-	c.Files2Remove = []string{}
+	c.FilesClass = make(map[string]FileClass, 0)
+	c.FilesSize = make(map[string]int64, 0)
+	c.FilesModTime = make(map[string]time.Time, 0)
 
 	// Retrieve the list of the files
-	files, err := c.BackendHandler.GetFilesList()
+	files, err := c.getFilesList(ctx)
 	if err != nil {
 		return err
 	}
 	c.ProcessedFiles = len(files)
 
-	if c.Specs.GetCleaner().HasExcludes() {
-		files, err = c.GetFilteredList(files)
-		if err != nil {
-			return err
+	for _, f := range files {
+		if filter != nil && !filter(f) {
+			continue
 		}
-	}
-
-	// Exclude repository files
-	repoRegex := []string{
-		"repository.meta.yaml.tar.*|repository.meta.yaml",
-		"repository.yaml",
-		"tree.tar.*|tree.tar",
-		"compilertree.tar.*|compilertree.tar",
-	}
-
-	metaFilesRegex := []string{
-		".*metadata.yaml",
-	}
 
-	pkgFilesRegex := []string{
-		".*package.tar|.*package.tar.*",
-	}
+		class := ClassifyFile(f)
+		c.FilesClass[f] = class
 
-	for _, f := range files {
-		if tmtools.RegexEntry(f, repoRegex) {
+		if class == FileClassIndex {
 			DebugC(fmt.Sprintf("Ignoring repository file %s", f))
 			continue
 		}
@@ -130,29 +192,104 @@ func (c *RepoKnife) Analyze() error {
 			DebugC(fmt.Sprintf("[%s] Analyzing...", f))
 		}
 
-		if tmtools.RegexEntry(f, metaFilesRegex) {
-
-			art, err := c.BackendHandler.GetMetadata(f)
+		switch class {
+		case FileClassMetadata:
+			art, err := c.getMetadata(ctx, f)
 			if err != nil {
 				return err
 			}
 
 			c.MetaMap[f] = art
-		} else if tmtools.RegexEntry(f, pkgFilesRegex) {
 
+		case FileClassTarball:
 			replaceRegex := regexp.MustCompile(
 				`.package.tar$|.package.tar.gz$|.package.tar.zst$`,
 			)
 
 			metaFile := replaceRegex.ReplaceAllString(f, ".metadata.yaml")
 			c.PkgsMap[f] = metaFile
+		}
+	}
 
-		} else {
-			// POST: file to remove
-			c.Files2Remove = append(c.Files2Remove, f)
+	if c.Cache != nil && filter == nil {
+		cached := []specs.RepoFile{}
+		for f, class := range c.FilesClass {
+			if class == FileClassMetadata {
+				cached = append(cached, c.getRepoFile(f))
+			}
+		}
+		if err := c.Cache.Prune(cached); err != nil {
+			Warning("Error on prune metadata cache: " + err.Error())
 		}
 	}
 
+	return nil
+}
+
+func (c *RepoKnife) getRepoFile(f string) specs.RepoFile {
+	return specs.RepoFile{
+		Name:         f,
+		Size:         c.FilesSize[f],
+		LastModified: c.FilesModTime[f],
+	}
+}
+
+// getMetadata returns the artifact of the metadata file from
+// the cache if available or from the backend.
+func (c *RepoKnife) getMetadata(ctx context.Context, f string) (*artifact.PackageArtifact, error) {
+	if c.Cache == nil {
+		return c.BackendHandler.GetMetadata(ctx, f)
+	}
+
+	rf := c.getRepoFile(f)
+	if art, ok := c.Cache.Get(rf); ok {
+		DebugC(fmt.Sprintf("[%s] Metadata loaded from cache.", f))
+		return art, nil
+	}
+
+	art, err := c.BackendHandler.GetMetadata(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.Cache.Put(rf, art); err != nil {
+		Warning(fmt.Sprintf("[%s] Error on write metadata cache: %s", f, err.Error()))
+	}
+
+	return art, nil
+}
+
+func (c *RepoKnife) Analyze(ctx context.Context) error {
+	var filter func(string) bool
+
+	// Reset previous values
+	c.Files2Remove = []string{}
+	c.Removals = make(map[string]RemoveReason, 0)
+	c.orphanMetaMap = make(map[string]*artifact.PackageArtifact, 0)
+
+	if c.Specs.GetCleaner().HasExcludes() {
+		filter = func(f string) bool { return !c.isExcludedFile(f) }
+	}
+
+	err := c.LoadMetadata(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	// Apply the cleaner policy to the unknown files. The sidecar files
+	// are checked at the end when the removals of the packages
+	// are known.
+	files := []string{}
+	for f, class := range c.FilesClass {
+		if class == FileClassUnknown {
+			files = append(files, f)
+		}
+	}
+	sort.Strings(files)
+	for _, f := range files {
+		c.applyFilePolicy(f, c.FilesClass[f])
+	}
+
 	// Check if there are all package for every metafile
 	meta2Remove := []string{}
 	for f, art := range c.MetaMap {
@@ -168,13 +305,17 @@ func (c *RepoKnife) Analyze() error {
 					"No tarball found for metafile %s. I delete metafile.",
 					f))
 			}
-			c.Files2Remove = append(c.Files2Remove, f)
+			c.markRemove(f, ReasonOrphanMetadata)
 			meta2Remove = append(meta2Remove, f)
 		}
 	}
 
+	// The orphan metafiles are already marked for removal and
+	// they are dropped from the MetaMap so that the next checks and
+	// the listing of the available packages don't consider them.
 	for _, m := range meta2Remove {
-		delete(c.PkgsMap, m)
+		c.orphanMetaMap[m] = c.MetaMap[m]
+		delete(c.MetaMap, m)
 	}
 
 	// Check if there are all metadata for every package tarball
@@ -189,7 +330,7 @@ func (c *RepoKnife) Analyze() error {
 					"No tarball file available for meta %s. I delete the tarball.",
 					f))
 			}
-			c.Files2Remove = append(c.Files2Remove, f)
+			c.markRemove(f, ReasonOrphanTarball)
 		}
 	}
 
@@ -199,9 +340,58 @@ func (c *RepoKnife) Analyze() error {
 		return err
 	}
 
+	c.CheckDuplicates()
+	c.CheckExcludedPackages()
+
+	err = c.CheckRequiredArtifacts()
+	if err != nil {
+		return err
+	}
+
+	c.CheckSidecarFiles()
+
 	return nil
 }
 
+// CheckSidecarFiles applies the cleaner policies to the signature and
+// checksum files. A sidecar file is handled by the policy of its class
+// only when the file it refers to is missing or marked for removal.
+// The sidecars of the index files and the aggregated checksum files
+// are always kept.
+func (c *RepoKnife) CheckSidecarFiles() {
+	files := []string{}
+	for f, class := range c.FilesClass {
+		if class == FileClassSignature || class == FileClassChecksum {
+			files = append(files, f)
+		}
+	}
+	sort.Strings(files)
+
+	for _, f := range files {
+		class := c.FilesClass[f]
+		parent := SidecarParent(f)
+
+		if parent == "" {
+			DebugC(fmt.Sprintf("[%s] Keeping %s file without parent.", f, class))
+			continue
+		}
+
+		if ClassifyFile(parent) == FileClassIndex {
+			DebugC(fmt.Sprintf("[%s] Keeping %s file of %s.", f, class, parent))
+			continue
+		}
+
+		_, exists := c.FilesClass[parent]
+		_, removed := c.Removals[parent]
+		if (exists && !removed) || c.isExcludedFile(parent) {
+			DebugC(fmt.Sprintf("[%s] Keeping %s file of %s.", f, class, parent))
+			continue
+		}
+
+		c.applyFilePolicy(f, class)
+	}
+}
+
 func (c *RepoKnife) CheckFilesWithTrees() error {
 
 	for m, art := range c.MetaMap {
@@ -231,8 +421,8 @@ func (c *RepoKnife) CheckFilesWithTrees() error {
 				))
 			}
 
-			c.Files2Remove = append(c.Files2Remove, m)
-			c.Files2Remove = append(c.Files2Remove, pkgFile)
+			c.markRemove(m, ReasonDroppedFromTree)
+			c.markRemove(pkgFile, ReasonDroppedFromTree)
 		}
 
 	}
@@ -244,10 +434,170 @@ func (c *RepoKnife) GetFilteredList(files []string) ([]string, error) {
 	ans := []string{}
 
 	for _, f := range files {
-		if !tmtools.RegexEntry(f, c.Specs.GetCleaner().Excludes) {
+		if !c.isExcludedFile(f) {
 			ans = append(ans, f)
 		}
 	}
 
 	return ans, nil
 }
+
+func (c *RepoKnife) isExcludedFile(f string) bool {
+	return tmtools.RegexEntry(f, c.Specs.GetCleaner().Excludes)
+}
+
+// getFilesList returns the list of the files of the repository and
+// stores the size of the files when the backend supports it.
+func (c *RepoKnife) getFilesList(ctx context.Context) ([]string, error) {
+	infoBackend, ok := c.BackendHandler.(specs.RepoBackendFilesInfo)
+	if !ok {
+		return c.BackendHandler.GetFilesList(ctx)
+	}
+
+	ans := []string{}
+	files, err := infoBackend.GetFilesInfo(ctx)
+	if err != nil {
+		return ans, err
+	}
+
+	for _, f := range files {
+		ans = append(ans, f.Name)
+		c.FilesSize[f.Name] = f.Size
+		c.FilesModTime[f.Name] = f.LastModified
+	}
+
+	return ans, nil
+}
+
+// GetFilePackage returns the package related to a metadata or
+// a tarball file. It returns an empty string if the package is unknown.
+func (c *RepoKnife) GetFilePackage(f string) string {
+	metaFile := f
+	if m, ok := c.PkgsMap[f]; ok {
+		metaFile = m
+	}
+
+	art, ok := c.MetaMap[metaFile]
+	if !ok {
+		art = c.orphanMetaMap[metaFile]
+	}
+
+	if p := artifactPackage(art); p != nil {
+		return p.HumanReadableString()
+	}
+
+	return ""
+}
+
+// applyFilePolicy applies the cleaner policy of the class to an
+// unknown file or to a sidecar file without a live parent.
+func (c *RepoKnife) applyFilePolicy(f string, class FileClass) {
+	switch c.Specs.GetCleaner().GetFilePolicy(string(class)) {
+	case specs.FilePolicyDelete:
+		reason := ReasonUnknownFile
+		if class == FileClassSignature {
+			reason = ReasonSignatureFile
+		} else if class == FileClassChecksum {
+			reason = ReasonChecksumFile
+		}
+		c.markRemove(f, reason)
+	case specs.FilePolicyWarn:
+		Warning(fmt.Sprintf("[%s] Found %s file. I keep it.", f, class))
+	default:
+		DebugC(fmt.Sprintf("[%s] Keeping %s file.", f, class))
+	}
+}
+
+// recordJournal adds an entry to the journal if it's configured.
+func (c *RepoKnife) recordJournal(e *JournalEntry) {
+	if c.Journal == nil {
+		return
+	}
+
+	if err := c.Journal.Record(e); err != nil {
+		Error(fmt.Sprintf("[%s] Error on write journal: %s", e.File, err.Error()))
+	}
+}
+
+// recordJournalIntents adds the intent entries of the operations
+// to the journal if it's configured. The operations must not be
+// executed if the intents aren't recorded.
+func (c *RepoKnife) recordJournalIntents(entries []*JournalEntry) error {
+	if c.Journal == nil {
+		return nil
+	}
+
+	for _, e := range entries {
+		e.Status = JournalStatusIntent
+	}
+
+	if err := c.Journal.RecordAll(entries); err != nil {
+		return errors.New("Error on write journal: " + err.Error())
+	}
+	return nil
+}
+
+// markRemove adds a file to the list of the files to remove.
+func (c *RepoKnife) markRemove(f string, reason RemoveReason) {
+	if _, ok := c.Removals[f]; ok {
+		return
+	}
+	c.Files2Remove = append(c.Files2Remove, f)
+	c.Removals[f] = reason
+}
+
+// keepFile drops a file from the list of the files to remove.
+func (c *RepoKnife) keepFile(f string) {
+	if _, ok := c.Removals[f]; !ok {
+		return
+	}
+	delete(c.Removals, f)
+	for idx := range c.Files2Remove {
+		if c.Files2Remove[idx] == f {
+			c.Files2Remove = append(c.Files2Remove[:idx], c.Files2Remove[idx+1:]...)
+			return
+		}
+	}
+}
+
+// CheckExcludedPackages keeps the metadata and the tarball of the
+// artifacts that match the exclude_pkgs of the cleaner.
+func (c *RepoKnife) CheckExcludedPackages() {
+	cleaner := c.Specs.GetCleaner()
+	if !cleaner.HasExcludePkgs() {
+		return
+	}
+
+	for _, metas := range []map[string]*artifact.PackageArtifact{
+		c.MetaMap, c.orphanMetaMap,
+	} {
+		for m, art := range metas {
+			p := artifactPackage(art)
+			if p == nil || !cleaner.IsExcludedPkg(p) {
+				continue
+			}
+
+			if _, ok := c.Removals[m]; ok {
+				if c.Verbose {
+					InfoC(fmt.Sprintf("[%s] Excluded by exclude_pkgs. I keep it.",
+						p.HumanReadableString()))
+				} else {
+					DebugC(fmt.Sprintf("[%s] Excluded by exclude_pkgs. I keep it.",
+						p.HumanReadableString()))
+				}
+			}
+
+			c.keepFile(m)
+			c.keepFile(filepath.Base(art.Path))
+		}
+	}
+}
+
+// GetRemovalsByReason returns the number of files to remove for reason.
+func (c *RepoKnife) GetRemovalsByReason() map[RemoveReason]int {
+	ans := make(map[RemoveReason]int, 0)
+	for _, f := range c.Files2Remove {
+		ans[c.Removals[f]]++
+	}
+	return ans
+}
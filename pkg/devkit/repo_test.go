@@ -0,0 +1,308 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"context"
+	"testing"
+
+	specs "github.com/macaroni-os/anise-repo-devkit/pkg/specs"
+)
+
+func TestAnalyzeConsistentRepository(t *testing.T) {
+	fixtures := []*fixture{
+		{Category: "app", Name: "foo", Version: "1.0", Compression: "zst",
+			InTree: true, WithMeta: true, WithTarball: true},
+		{Category: "app", Name: "bar", Version: "2.1", Compression: "gz",
+			InTree: true, WithMeta: true, WithTarball: true},
+		{Category: "dev", Name: "baz", Version: "0.1",
+			InTree: true, WithMeta: true, WithTarball: true},
+	}
+
+	files := analyzeFixtures(t, specs.NewAniseRDConfig(), fixtures,
+		"repository.yaml", "repository.meta.yaml.tar.zst",
+		"tree.tar.zst", "compilertree.tar.zst",
+	)
+	assertFiles(t, files)
+}
+
+func TestAnalyzeOrphanMetadata(t *testing.T) {
+	fixtures := []*fixture{
+		{Category: "app", Name: "foo", Version: "1.0", Compression: "zst",
+			InTree: true, WithMeta: true, WithTarball: false},
+		// Orphan metadata of a package dropped from the tree is
+		// reported only one time and without the missing tarball.
+		{Category: "app", Name: "old", Version: "1.0", Compression: "zst",
+			InTree: false, WithMeta: true, WithTarball: false},
+	}
+
+	files := analyzeFixtures(t, specs.NewAniseRDConfig(), fixtures)
+	assertFiles(t, files, fixtures[0].MetaFile(), fixtures[1].MetaFile())
+}
+
+func TestAnalyzeOrphanMetadataNotAvailable(t *testing.T) {
+	fixtures := []*fixture{
+		{Category: "app", Name: "foo", Version: "1.0", Compression: "zst",
+			InTree: true, WithMeta: true, WithTarball: true},
+		// The package is in the tree but the tarball is missing.
+		{Category: "app", Name: "bar", Version: "1.0", Compression: "zst",
+			InTree: true, WithMeta: true, WithTarball: false},
+	}
+
+	knife, _ := newFixtureKnife(t, specs.NewAniseRDConfig(), fixtures)
+	list := &RepoList{RepoKnife: knife}
+	pkgs, err := list.ListPkgsAvailable(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The orphan metadata must not be seen by the next steps.
+	if len(pkgs) != 1 || pkgs[0].HumanReadableString() != "app/foo-1.0" {
+		t.Fatalf("Unexpected available packages %v", pkgs)
+	}
+	if _, ok := knife.MetaMap[fixtures[1].MetaFile()]; ok {
+		t.Fatalf("Orphan metadata %s still in the metadata map", fixtures[1].MetaFile())
+	}
+	if knife.Removals[fixtures[1].MetaFile()] != ReasonOrphanMetadata {
+		t.Fatalf("Unexpected reason %s", knife.Removals[fixtures[1].MetaFile()])
+	}
+	assertFiles(t, knife.Files2Remove, fixtures[1].MetaFile())
+}
+
+func TestAnalyzeOrphanTarball(t *testing.T) {
+	fixtures := []*fixture{
+		{Category: "app", Name: "foo", Version: "1.0", Compression: "zst",
+			InTree: true, WithMeta: false, WithTarball: true},
+		{Category: "app", Name: "bar", Version: "1.0", Compression: "gz",
+			InTree: true, WithMeta: false, WithTarball: true},
+		{Category: "app", Name: "baz", Version: "1.0",
+			InTree: false, WithMeta: false, WithTarball: true},
+	}
+
+	files := analyzeFixtures(t, specs.NewAniseRDConfig(), fixtures)
+	assertFiles(t, files,
+		fixtures[0].Tarball(), fixtures[1].Tarball(), fixtures[2].Tarball())
+}
+
+func TestAnalyzeRemovedFromTree(t *testing.T) {
+	fixtures := []*fixture{
+		{Category: "app", Name: "foo", Version: "1.0", Compression: "zst",
+			InTree: false, WithMeta: true, WithTarball: true},
+		{Category: "app", Name: "foo", Version: "1.1", Compression: "zst",
+			InTree: true, WithMeta: true, WithTarball: true},
+	}
+
+	knife, _ := newFixtureKnife(t, specs.NewAniseRDConfig(), fixtures)
+	if err := knife.Analyze(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	assertFiles(t, knife.Files2Remove, fixtures[0].MetaFile(), fixtures[0].Tarball())
+	if len(knife.MetaMap) != 2 {
+		t.Fatalf("Expected 2 metadata, got %d", len(knife.MetaMap))
+	}
+}
+
+func TestAnalyzeUnknownFiles(t *testing.T) {
+	fixtures := []*fixture{
+		{Category: "app", Name: "foo", Version: "1.0", Compression: "zst",
+			InTree: true, WithMeta: true, WithTarball: true},
+	}
+
+	extra := []string{"README.txt", fixtures[0].Tarball() + ".sig", "SHA256SUMS"}
+
+	// By default all files are kept.
+	files := analyzeFixtures(t, specs.NewAniseRDConfig(), fixtures, extra...)
+	assertFiles(t, files)
+
+	s := specs.NewAniseRDConfig()
+	s.Cleaner.FilesPolicy.Unknown = specs.FilePolicyDelete
+	files = analyzeFixtures(t, s, fixtures, extra...)
+	assertFiles(t, files, "README.txt")
+
+	// The sidecars of live tarballs and the aggregated checksum
+	// files are kept.
+	s.Cleaner.FilesPolicy.Signature = specs.FilePolicyDelete
+	s.Cleaner.FilesPolicy.Checksum = specs.FilePolicyDelete
+	files = analyzeFixtures(t, s, fixtures, extra...)
+	assertFiles(t, files, "README.txt")
+}
+
+func sidecarsConfig() *specs.AniseRDConfig {
+	s := specs.NewAniseRDConfig()
+	s.Cleaner.FilesPolicy.Signature = specs.FilePolicyDelete
+	s.Cleaner.FilesPolicy.Checksum = specs.FilePolicyDelete
+	return s
+}
+
+func TestAnalyzeSidecarsOfLiveFiles(t *testing.T) {
+	fixtures := []*fixture{
+		{Category: "app", Name: "foo", Version: "1.0", Compression: "zst",
+			InTree: true, WithMeta: true, WithTarball: true},
+	}
+
+	files := analyzeFixtures(t, sidecarsConfig(), fixtures,
+		fixtures[0].Tarball()+".sig",
+		fixtures[0].Tarball()+".sha256",
+		fixtures[0].Tarball()+".sha256.asc",
+		fixtures[0].MetaFile()+".sig",
+	)
+	assertFiles(t, files)
+}
+
+func TestAnalyzeSidecarsOfIndexFiles(t *testing.T) {
+	fixtures := []*fixture{
+		{Category: "app", Name: "foo", Version: "1.0", Compression: "zst",
+			InTree: true, WithMeta: true, WithTarball: true},
+	}
+
+	// The index sidecars are kept even if the index file is missing.
+	files := analyzeFixtures(t, sidecarsConfig(), fixtures,
+		"repository.yaml", "repository.yaml.sig",
+		"repository.meta.yaml.tar.zst.sha256",
+		"tree.tar.zst.sha256.asc",
+	)
+	assertFiles(t, files)
+}
+
+func TestAnalyzeSidecarsOfRemovedFiles(t *testing.T) {
+	fixtures := []*fixture{
+		{Category: "app", Name: "foo", Version: "1.0", Compression: "zst",
+			InTree: false, WithMeta: true, WithTarball: true},
+		{Category: "app", Name: "foo", Version: "1.1", Compression: "zst",
+			InTree: true, WithMeta: true, WithTarball: true},
+	}
+
+	extra := []string{
+		fixtures[0].Tarball() + ".sig",
+		fixtures[0].Tarball() + ".sha256",
+		fixtures[0].MetaFile() + ".asc",
+		fixtures[1].Tarball() + ".sig",
+	}
+
+	knife, _ := newFixtureKnife(t, sidecarsConfig(), fixtures, extra...)
+	if err := knife.Analyze(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	assertFiles(t, knife.Files2Remove,
+		fixtures[0].MetaFile(), fixtures[0].Tarball(),
+		extra[0], extra[1], extra[2])
+	if knife.Removals[extra[0]] != ReasonSignatureFile ||
+		knife.Removals[extra[1]] != ReasonChecksumFile {
+		t.Fatalf("Unexpected reasons %v", knife.Removals)
+	}
+
+	// With the default policies the sidecars are kept.
+	files := analyzeFixtures(t, specs.NewAniseRDConfig(), fixtures, extra...)
+	assertFiles(t, files, fixtures[0].MetaFile(), fixtures[0].Tarball())
+}
+
+func TestAnalyzeSidecarsOfMissingFiles(t *testing.T) {
+	fixtures := []*fixture{
+		{Category: "app", Name: "foo", Version: "1.0", Compression: "zst",
+			InTree: true, WithMeta: true, WithTarball: true},
+	}
+
+	files := analyzeFixtures(t, sidecarsConfig(), fixtures,
+		"app-bar-1.0.package.tar.zst.sig",
+		"app-bar-1.0.package.tar.zst.sha256",
+		"SHA256SUMS", "SHA256SUMS.asc",
+	)
+	assertFiles(t, files,
+		"app-bar-1.0.package.tar.zst.sig",
+		"app-bar-1.0.package.tar.zst.sha256")
+}
+
+func TestClassifyFile(t *testing.T) {
+	for f, class := range map[string]FileClass{
+		"repository.yaml":                    FileClassIndex,
+		"repository.meta.yaml.tar.zst":       FileClassIndex,
+		"tree.tar.zst":                       FileClassIndex,
+		"foo-app-1.0.metadata.yaml":          FileClassMetadata,
+		"foo-app-1.0.package.tar.zst":        FileClassTarball,
+		"foo-app-1.0.package.tar":            FileClassTarball,
+		"foo-app-1.0.package.tar.zst.asc":    FileClassSignature,
+		"repository.yaml.sig":                FileClassSignature,
+		"foo-app-1.0.package.tar.zst.sha256": FileClassChecksum,
+		"SHA256SUMS":                         FileClassChecksum,
+		"README.txt":                         FileClassUnknown,
+	} {
+		if c := ClassifyFile(f); c != class {
+			t.Errorf("File %s: expected %s, got %s", f, class, c)
+		}
+	}
+}
+
+func TestSidecarParent(t *testing.T) {
+	for f, parent := range map[string]string{
+		"foo-app-1.0.package.tar.zst.asc":        "foo-app-1.0.package.tar.zst",
+		"foo-app-1.0.package.tar.zst.sha256.asc": "foo-app-1.0.package.tar.zst",
+		"repository.yaml.sig":                    "repository.yaml",
+		"SHA256SUMS":                             "",
+		"SHA256SUMS.asc":                         "",
+		"foo-app-1.0.package.tar.zst":            "",
+	} {
+		if p := SidecarParent(f); p != parent {
+			t.Errorf("File %s: expected parent %q, got %q", f, parent, p)
+		}
+	}
+}
+
+func TestAnalyzeExcludes(t *testing.T) {
+	fixtures := []*fixture{
+		{Category: "app", Name: "foo", Version: "1.0", Compression: "zst",
+			InTree: false, WithMeta: true, WithTarball: true},
+		{Category: "app", Name: "bar", Version: "1.0", Compression: "zst",
+			InTree: false, WithMeta: true, WithTarball: true},
+	}
+
+	s := specs.NewAniseRDConfig()
+	s.Cleaner.Excludes = []string{"^foo-app-", "README"}
+	s.Cleaner.FilesPolicy.Unknown = specs.FilePolicyDelete
+
+	files := analyzeFixtures(t, s, fixtures, "README.txt")
+	assertFiles(t, files, fixtures[1].MetaFile(), fixtures[1].Tarball())
+}
+
+func TestAnalyzeExcludePkgs(t *testing.T) {
+	fixtures := []*fixture{
+		{Category: "app", Name: "foo", Version: "1.0", Compression: "zst",
+			InTree: false, WithMeta: true, WithTarball: true},
+		{Category: "app", Name: "foo", Version: "2.0", Compression: "gz",
+			InTree: false, WithMeta: true, WithTarball: true},
+		{Category: "app", Name: "bar", Version: "1.0", Compression: "zst",
+			InTree: false, WithMeta: true, WithTarball: false},
+		{Category: "app", Name: "baz", Version: "1.0", Compression: "zst",
+			InTree: false, WithMeta: true, WithTarball: true},
+	}
+
+	s := specs.NewAniseRDConfig()
+	s.Cleaner.ExcludePkgs = []specs.AnisePackage{
+		{Category: "app", Name: "foo", Version: ">=2.0"},
+		{Category: "app", Name: "bar"},
+	}
+
+	files := analyzeFixtures(t, s, fixtures)
+	assertFiles(t, files,
+		fixtures[0].MetaFile(), fixtures[0].Tarball(),
+		fixtures[3].MetaFile(), fixtures[3].Tarball(),
+	)
+}
+
+func TestGetFilteredList(t *testing.T) {
+	s := specs.NewAniseRDConfig()
+	s.Cleaner.Excludes = []string{"^foo", "[.]sha256$"}
+
+	knife := NewRepoKnifeWithBackend(s, nil)
+	files, err := knife.GetFilteredList([]string{
+		"foo-app-1.0.package.tar", "bar-app-1.0.package.tar",
+		"bar-app-1.0.package.tar.sha256",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertFiles(t, files, "bar-app-1.0.package.tar")
+}
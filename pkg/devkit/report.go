@@ -0,0 +1,185 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	units "github.com/docker/go-units"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	ReportFormatJson     = "json"
+	ReportFormatYaml     = "yaml"
+	ReportFormatMarkdown = "markdown"
+
+	ReportStatusRemovable = "removable"
+	ReportStatusRemoved   = "removed"
+	ReportStatusFailed    = "failed"
+	ReportStatusSkipped   = "skipped"
+)
+
+// ReportFile describes a file removed or to remove.
+type ReportFile struct {
+	File      string `json:"file" yaml:"file"`
+	VersionID string `json:"version_id,omitempty" yaml:"version_id,omitempty"`
+	Package   string `json:"package,omitempty" yaml:"package,omitempty"`
+	Reason    string `json:"reason" yaml:"reason"`
+	Size      int64  `json:"size" yaml:"size"`
+	// Storage reclaimed by the removal of the file.
+	Reclaimed int64  `json:"reclaimed,omitempty" yaml:"reclaimed,omitempty"`
+	Status    string `json:"status" yaml:"status"`
+	Error     string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// Report is the structured report of a clean or purge run.
+type Report struct {
+	Command        string         `json:"command" yaml:"command"`
+	Date           time.Time      `json:"date" yaml:"date"`
+	DryRun         bool           `json:"dry_run" yaml:"dry_run"`
+	ProcessedFiles int            `json:"processed_files" yaml:"processed_files"`
+	Files          []ReportFile   `json:"files" yaml:"files"`
+	Reasons        map[string]int `json:"reasons" yaml:"reasons"`
+	RemovedFiles   int            `json:"removed_files" yaml:"removed_files"`
+	FailedFiles    int            `json:"failed_files" yaml:"failed_files"`
+	// Sum of the size of the files to remove.
+	TotalSize int64 `json:"total_size" yaml:"total_size"`
+	// Storage reclaimed by the removed files.
+	ReclaimedSize int64 `json:"reclaimed_size" yaml:"reclaimed_size"`
+	// The trees used by the run.
+	Trees []*TreeSource `json:"trees,omitempty" yaml:"trees,omitempty"`
+	// The trees with the compiler definitions used by the run.
+	BuildTrees []*TreeSource `json:"build_trees,omitempty" yaml:"build_trees,omitempty"`
+}
+
+func NewReport(command string, dryRun bool) *Report {
+	return &Report{
+		Command: command,
+		Date:    time.Now().UTC(),
+		DryRun:  dryRun,
+		Files:   []ReportFile{},
+		Reasons: make(map[string]int, 0),
+	}
+}
+
+// AddFile adds a file to the report and updates the totals.
+func (r *Report) AddFile(f ReportFile) {
+	r.Files = append(r.Files, f)
+	r.Reasons[f.Reason]++
+	r.TotalSize += f.Size
+
+	switch f.Status {
+	case ReportStatusRemoved:
+		r.RemovedFiles++
+		r.ReclaimedSize += f.Reclaimed
+	case ReportStatusFailed:
+		r.FailedFiles++
+	}
+}
+
+// Write writes the report in the specified format: json|yaml|markdown.
+func (r *Report) Write(w io.Writer, format string) error {
+	var data []byte
+	var err error
+
+	switch format {
+	case ReportFormatJson:
+		data, err = json.MarshalIndent(r, "", "  ")
+		data = append(data, '\n')
+	case ReportFormatYaml:
+		data, err = yaml.Marshal(r)
+	case ReportFormatMarkdown:
+		data = []byte(r.markdown())
+	default:
+		return errors.New(fmt.Sprintf("Invalid report format %s", format))
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+func (r *Report) markdown() string {
+	var b strings.Builder
+
+	mode := "run"
+	if r.DryRun {
+		mode = "dry-run"
+	}
+
+	fmt.Fprintf(&b, "# Repository %s report\n\n", r.Command)
+	fmt.Fprintf(&b, "- Date: %s\n", r.Date.Format(time.RFC3339))
+	fmt.Fprintf(&b, "- Mode: %s\n", mode)
+	fmt.Fprintf(&b, "- Processed files: %d\n", r.ProcessedFiles)
+	fmt.Fprintf(&b, "- Files to remove: %d (%s)\n", len(r.Files),
+		units.BytesSize(float64(r.TotalSize)))
+	if !r.DryRun {
+		fmt.Fprintf(&b, "- Removed files: %d\n", r.RemovedFiles)
+		fmt.Fprintf(&b, "- Failed files: %d\n", r.FailedFiles)
+		fmt.Fprintf(&b, "- Storage reclaimed: %s\n",
+			units.BytesSize(float64(r.ReclaimedSize)))
+	}
+
+	for _, t := range r.Trees {
+		writeReportTree(&b, "Tree", t)
+	}
+	for _, t := range r.BuildTrees {
+		writeReportTree(&b, "Build tree", t)
+	}
+
+	if len(r.Reasons) > 0 {
+		reasons := []string{}
+		for reason := range r.Reasons {
+			reasons = append(reasons, reason)
+		}
+		sort.Strings(reasons)
+
+		b.WriteString("\n## Reasons\n\n| Reason | Files |\n|---|---:|\n")
+		for _, reason := range reasons {
+			fmt.Fprintf(&b, "| %s | %d |\n", reason, r.Reasons[reason])
+		}
+	}
+
+	if len(r.Files) > 0 {
+		b.WriteString("\n## Files\n\n| File | Package | Reason | Size | Status |\n")
+		b.WriteString("|---|---|---|---:|---|\n")
+		for _, f := range r.Files {
+			file := f.File
+			if f.VersionID != "" {
+				file += " (" + f.VersionID + ")"
+			}
+			status := f.Status
+			if f.Error != "" {
+				status += ": " + f.Error
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n",
+				markdownEscape(file), markdownEscape(f.Package), f.Reason,
+				units.BytesSize(float64(f.Size)), markdownEscape(status))
+		}
+	}
+
+	return b.String()
+}
+
+func markdownEscape(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
+
+func writeReportTree(b *strings.Builder, label string, t *TreeSource) {
+	fmt.Fprintf(b, "- %s: %s", label, t.Source)
+	if t.Revision != "" {
+		fmt.Fprintf(b, " (%s)", t.Revision)
+	}
+	b.WriteString("\n")
+}
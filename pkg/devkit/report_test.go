@@ -0,0 +1,155 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCleanerReport(t *testing.T) {
+	cleaner, b := newFixtureCleaner(t, cleanerFixtures, false)
+	cleaner.Force = true
+	b.AddFile(cleanerFixtures[0].Tarball(), make([]byte, 100))
+	b.Errors[cleanerFixtures[2].Tarball()] = errors.New("permission denied")
+	metaSize := int64(len(b.Files[cleanerFixtures[0].MetaFile()]))
+
+	if err := cleaner.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	report := cleaner.GetReport()
+	if len(report.Files) != 3 || report.RemovedFiles != 2 || report.FailedFiles != 1 {
+		t.Fatalf("Unexpected report %+v", report)
+	}
+	if report.Reasons[string(ReasonDroppedFromTree)] != 2 ||
+		report.Reasons[string(ReasonOrphanTarball)] != 1 {
+		t.Fatalf("Unexpected reasons %v", report.Reasons)
+	}
+
+	if report.ReclaimedSize != 100+metaSize {
+		t.Fatalf("Expected reclaimed size %d, got %d", 100+metaSize,
+			report.ReclaimedSize)
+	}
+	if cleaner.ReclaimedSize != report.ReclaimedSize {
+		t.Fatalf("Expected cleaner reclaimed size %d, got %d",
+			report.ReclaimedSize, cleaner.ReclaimedSize)
+	}
+
+	for _, f := range report.Files {
+		switch f.File {
+		case cleanerFixtures[0].Tarball(), cleanerFixtures[0].MetaFile():
+			if f.Package != "app/foo-1.0" || f.Status != ReportStatusRemoved {
+				t.Fatalf("Unexpected file %+v", f)
+			}
+		case cleanerFixtures[2].Tarball():
+			if f.Package != "" || f.Status != ReportStatusFailed || f.Error == "" {
+				t.Fatalf("Unexpected file %+v", f)
+			}
+		default:
+			t.Fatalf("Unexpected file %s", f.File)
+		}
+	}
+}
+
+func TestCleanerReportDryRun(t *testing.T) {
+	cleaner, b := newFixtureCleaner(t, cleanerFixtures, true)
+	b.AddFile(cleanerFixtures[0].Tarball(), make([]byte, 100))
+
+	if err := cleaner.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	report := cleaner.GetReport()
+	if !report.DryRun || report.RemovedFiles != 0 || report.ReclaimedSize != 0 {
+		t.Fatalf("Unexpected report %+v", report)
+	}
+	if report.TotalSize < 100 {
+		t.Fatalf("Expected total size >= 100, got %d", report.TotalSize)
+	}
+	for _, f := range report.Files {
+		if f.Status != ReportStatusRemovable {
+			t.Fatalf("Unexpected status %s for %s", f.Status, f.File)
+		}
+	}
+}
+
+func TestCleanerReportVersioned(t *testing.T) {
+	cleaner, b := newFixtureCleaner(t, cleanerFixtures, false)
+	cleaner.Force = true
+	b.Versioned = true
+	b.AddFile(cleanerFixtures[0].Tarball(), make([]byte, 100))
+
+	if err := cleaner.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The delete markers don't reclaim storage.
+	report := cleaner.GetReport()
+	if report.RemovedFiles != 3 || report.TotalSize == 0 || report.ReclaimedSize != 0 {
+		t.Fatalf("Unexpected report %+v", report)
+	}
+	if v := b.GetVersions(cleanerFixtures[0].Tarball()); len(v) != 3 ||
+		!v[2].IsDeleteMarker {
+		t.Fatalf("Expected a delete marker, got %+v", v)
+	}
+}
+
+func TestReportWrite(t *testing.T) {
+	report := NewReport("clean", false)
+	report.AddFile(ReportFile{
+		File: "foo-app-1.0.package.tar.zst", Package: "app/foo-1.0",
+		Reason: string(ReasonDroppedFromTree), Size: 2048,
+		Reclaimed: 2048, Status: ReportStatusRemoved,
+	})
+	report.Trees = []*TreeSource{{Source: "/trees/runtime", Kind: TreeKindLocal}}
+	report.BuildTrees = []*TreeSource{{
+		Source: "repository:compilertree.tar.zst", Kind: TreeKindTarball,
+		Revision: "sha256:1234",
+	}}
+
+	var buf bytes.Buffer
+	if err := report.Write(&buf, ReportFormatJson); err != nil {
+		t.Fatal(err)
+	}
+	decoded := &Report{}
+	if err := json.Unmarshal(buf.Bytes(), decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.ReclaimedSize != 2048 || len(decoded.Files) != 1 ||
+		len(decoded.BuildTrees) != 1 || decoded.BuildTrees[0].Revision != "sha256:1234" {
+		t.Fatalf("Unexpected decoded report %+v", decoded)
+	}
+
+	buf.Reset()
+	if err := report.Write(&buf, ReportFormatYaml); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "reclaimed_size: 2048") {
+		t.Fatalf("Unexpected yaml report:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := report.Write(&buf, ReportFormatMarkdown); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(),
+		"| foo-app-1.0.package.tar.zst | app/foo-1.0 | dropped-from-tree | 2KiB | removed |") {
+		t.Fatalf("Unexpected markdown report:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), "- Tree: /trees/runtime\n") ||
+		!strings.Contains(buf.String(),
+			"- Build tree: repository:compilertree.tar.zst (sha256:1234)\n") {
+		t.Fatalf("Unexpected markdown trees:\n%s", buf.String())
+	}
+
+	if err := report.Write(&buf, "xml"); err == nil {
+		t.Fatal("Expected error on invalid format")
+	}
+}
@@ -0,0 +1,197 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	specs "github.com/macaroni-os/anise-repo-devkit/pkg/specs"
+	"github.com/macaroni-os/anise-repo-devkit/pkg/version"
+
+	. "github.com/geaaru/luet/pkg/logger"
+	anise_pkg "github.com/geaaru/luet/pkg/package"
+	artifact "github.com/geaaru/luet/pkg/v2/compiler/types/artifact"
+)
+
+// versionAdmit returns true if the version v satisfies the version
+// selector of a require. An empty selector admits all versions.
+func versionAdmit(selector, v string) bool {
+	if selector == "" {
+		return true
+	}
+
+	s, err := version.ParseVersion(selector)
+	if err != nil {
+		return false
+	}
+	pv, err := version.ParseVersion(v)
+	if err != nil {
+		return false
+	}
+
+	admit, err := version.PackageAdmit(s, pv)
+	if err != nil {
+		DebugC(fmt.Sprintf("Error on compare version %s with %s: %s",
+			v, selector, err.Error()))
+		return false
+	}
+
+	return admit
+}
+
+// packageSatisfies returns true if the package p or one of the packages
+// provided by p satisfies the requirement req.
+func packageSatisfies(req, p *anise_pkg.DefaultPackage) bool {
+	if req.GetCategory() == p.GetCategory() && req.GetName() == p.GetName() {
+		if versionAdmit(req.GetVersion(), p.GetVersion()) {
+			return true
+		}
+	}
+
+	for _, prov := range p.Provides {
+		if req.GetCategory() != prov.GetCategory() || req.GetName() != prov.GetName() {
+			continue
+		}
+		if prov.GetVersion() == "" || versionAdmit(req.GetVersion(), prov.GetVersion()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isNewerVersion returns true if the version a is greater than b.
+func isNewerVersion(a, b string) bool {
+	return versionAdmit(">"+b, a)
+}
+
+func artifactPackage(art *artifact.PackageArtifact) *anise_pkg.DefaultPackage {
+	if art == nil {
+		return nil
+	}
+	return art.GetPackage()
+}
+
+// CheckRequiredArtifacts resolves the requires of all artifacts that
+// remain in the repository against the remaining artifacts. When a
+// requirement is satisfied only by an artifact marked for removal the
+// artifact is kept or reported based on the cleaner required_policy.
+func (c *RepoKnife) CheckRequiredArtifacts() error {
+	c.RequiredArtifacts = make(map[string]string, 0)
+
+	policy := c.Specs.GetCleaner().GetRequiredPolicy()
+	if policy == specs.RequiredPolicyIgnore {
+		return nil
+	}
+
+	mRemove := make(map[string]bool, len(c.Files2Remove))
+	for _, f := range c.Files2Remove {
+		mRemove[f] = true
+	}
+
+	// Sort the metafiles to have reproducible results.
+	metas := []string{}
+	for m := range c.MetaMap {
+		metas = append(metas, m)
+	}
+	sort.Strings(metas)
+
+	remaining := []string{}
+	candidates := []string{}
+	for _, m := range metas {
+		if artifactPackage(c.MetaMap[m]) == nil {
+			continue
+		}
+		if _, ok := mRemove[m]; ok {
+			candidates = append(candidates, m)
+		} else {
+			remaining = append(remaining, m)
+		}
+	}
+
+	queue := append([]string{}, remaining...)
+	for len(queue) > 0 {
+		m := queue[0]
+		queue = queue[1:]
+		p := artifactPackage(c.MetaMap[m])
+
+		for _, req := range p.GetRequires() {
+			if c.isRequireSatisfied(req, remaining) {
+				continue
+			}
+
+			// Search the best candidate between the artifacts to remove.
+			best := ""
+			for _, cand := range candidates {
+				cp := artifactPackage(c.MetaMap[cand])
+				if !packageSatisfies(req, cp) {
+					continue
+				}
+				if best == "" || isNewerVersion(cp.GetVersion(),
+					artifactPackage(c.MetaMap[best]).GetVersion()) {
+					best = cand
+				}
+			}
+
+			if best == "" {
+				Warning(fmt.Sprintf(
+					"[%s] The requirement %s is not satisfied by the repository.",
+					p.HumanReadableString(), req.HumanReadableString()))
+				continue
+			}
+
+			bp := artifactPackage(c.MetaMap[best])
+			if _, ok := c.RequiredArtifacts[best]; !ok {
+				c.RequiredArtifacts[best] = p.HumanReadableString()
+			}
+
+			if policy == specs.RequiredPolicyWarn {
+				Warning(fmt.Sprintf(
+					"[%s] The package %s is still required by %s.",
+					best, bp.HumanReadableString(), p.HumanReadableString()))
+				// Avoid to report the same package multiple times.
+				remaining = append(remaining, best)
+				continue
+			}
+
+			if c.Verbose {
+				InfoC(fmt.Sprintf(
+					"[%s] Still required by %s. I keep it.",
+					bp.HumanReadableString(), p.HumanReadableString()))
+			} else {
+				DebugC(fmt.Sprintf(
+					"[%s] Still required by %s. I keep it.",
+					bp.HumanReadableString(), p.HumanReadableString()))
+			}
+
+			c.keepFile(best)
+			c.keepFile(filepath.Base(c.MetaMap[best].Path))
+
+			remaining = append(remaining, best)
+			queue = append(queue, best)
+
+			// Drop the candidate
+			for idx, cand := range candidates {
+				if cand == best {
+					candidates = append(candidates[:idx], candidates[idx+1:]...)
+					break
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *RepoKnife) isRequireSatisfied(req *anise_pkg.DefaultPackage, metas []string) bool {
+	for _, m := range metas {
+		if packageSatisfies(req, artifactPackage(c.MetaMap[m])) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"testing"
+
+	specs "github.com/macaroni-os/anise-repo-devkit/pkg/specs"
+
+	anise_pkg "github.com/geaaru/luet/pkg/package"
+)
+
+func requiresFixtures() []*fixture {
+	return []*fixture{
+		{Category: "app", Name: "foo", Version: "1.1", Compression: "zst",
+			InTree: true, WithMeta: true, WithTarball: true,
+			Requires: []*anise_pkg.DefaultPackage{
+				anise_pkg.NewPackageWithCat("dev", "lib", ">=1.0", nil, nil),
+			},
+		},
+		// Only the old versions of dev/lib are published.
+		{Category: "dev", Name: "lib", Version: "1.0", Compression: "zst",
+			InTree: false, WithMeta: true, WithTarball: true,
+			Requires: []*anise_pkg.DefaultPackage{
+				anise_pkg.NewPackageWithCat("sys", "base", "", nil, nil),
+			},
+		},
+		{Category: "dev", Name: "lib", Version: "1.2", Compression: "zst",
+			InTree: false, WithMeta: true, WithTarball: true,
+			Requires: []*anise_pkg.DefaultPackage{
+				anise_pkg.NewPackageWithCat("sys", "base", "", nil, nil),
+			},
+		},
+		{Category: "dev", Name: "lib", Version: "2.0",
+			InTree: true, WithMeta: false, WithTarball: false},
+		{Category: "sys", Name: "base", Version: "1.0", Compression: "zst",
+			InTree: false, WithMeta: true, WithTarball: true},
+		{Category: "app", Name: "unused", Version: "1.0", Compression: "zst",
+			InTree: false, WithMeta: true, WithTarball: true},
+	}
+}
+
+func TestCheckRequiredArtifactsKeep(t *testing.T) {
+	fixtures := requiresFixtures()
+
+	knife, _ := newFixtureKnife(t, specs.NewAniseRDConfig(), fixtures)
+	if err := knife.Analyze(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The newest dev/lib and its requirement are kept.
+	assertFiles(t, knife.Files2Remove,
+		fixtures[1].MetaFile(), fixtures[1].Tarball(),
+		fixtures[5].MetaFile(), fixtures[5].Tarball(),
+	)
+
+	if knife.RequiredArtifacts[fixtures[2].MetaFile()] != "app/foo-1.1" {
+		t.Fatalf("Unexpected required artifacts %v", knife.RequiredArtifacts)
+	}
+	if knife.RequiredArtifacts[fixtures[4].MetaFile()] != "dev/lib-1.2" {
+		t.Fatalf("Unexpected required artifacts %v", knife.RequiredArtifacts)
+	}
+}
+
+func TestCheckRequiredArtifactsWarn(t *testing.T) {
+	fixtures := requiresFixtures()
+
+	s := specs.NewAniseRDConfig()
+	s.Cleaner.RequiredPolicy = specs.RequiredPolicyWarn
+
+	knife, _ := newFixtureKnife(t, s, fixtures)
+	if err := knife.Analyze(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	assertFiles(t, knife.Files2Remove,
+		fixtures[1].MetaFile(), fixtures[1].Tarball(),
+		fixtures[2].MetaFile(), fixtures[2].Tarball(),
+		fixtures[4].MetaFile(), fixtures[4].Tarball(),
+		fixtures[5].MetaFile(), fixtures[5].Tarball(),
+	)
+
+	if _, ok := knife.RequiredArtifacts[fixtures[2].MetaFile()]; !ok {
+		t.Fatalf("Expected dev/lib-1.2 reported, got %v", knife.RequiredArtifacts)
+	}
+}
+
+func TestPackageSatisfies(t *testing.T) {
+	p := anise_pkg.NewPackageWithCat("dev", "lib", "1.2", nil, nil)
+	p.Provides = []*anise_pkg.DefaultPackage{
+		anise_pkg.NewPackageWithCat("virtual", "lib", "", nil, nil),
+	}
+
+	for _, tc := range []struct {
+		req      *anise_pkg.DefaultPackage
+		expected bool
+	}{
+		{anise_pkg.NewPackageWithCat("dev", "lib", "", nil, nil), true},
+		{anise_pkg.NewPackageWithCat("dev", "lib", ">=1.0", nil, nil), true},
+		{anise_pkg.NewPackageWithCat("dev", "lib", "<1.2", nil, nil), false},
+		{anise_pkg.NewPackageWithCat("dev", "other", ">=0", nil, nil), false},
+		{anise_pkg.NewPackageWithCat("virtual", "lib", ">=0", nil, nil), true},
+	} {
+		if got := packageSatisfies(tc.req, p); got != tc.expected {
+			t.Errorf("%s: expected %v, got %v", tc.req.HumanReadableString(),
+				tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	. "github.com/geaaru/luet/pkg/logger"
+	anise_pkg "github.com/geaaru/luet/pkg/package"
+	artifact "github.com/geaaru/luet/pkg/v2/compiler/types/artifact"
+)
+
+type SBOMFormat string
+
+const (
+	SBOMFormatSPDX      SBOMFormat = "spdx"
+	SBOMFormatCycloneDX SBOMFormat = "cyclonedx"
+)
+
+// SBOMOptions defines the content of the SBOM.
+type SBOMOptions struct {
+	Format SBOMFormat
+	// Name of the SBOM document, for example the repository name.
+	Name string
+	// Include the files shipped by every package. Supported only by
+	// the CycloneDX format.
+	WithFiles bool
+	// Creation time of the document. Default is now.
+	Created time.Time
+}
+
+// sbomPackage is a package of the repository with the ids of the
+// packages that satisfy its requires.
+type sbomPackage struct {
+	ID       string
+	Package  *anise_pkg.DefaultPackage
+	Artifact *artifact.PackageArtifact
+	Tarball  string
+	Requires []string
+}
+
+var sbomIDRegex = regexp.MustCompile(`[^a-zA-Z0-9.-]+`)
+
+func sbomID(s string) string {
+	return sbomIDRegex.ReplaceAllString(s, "-")
+}
+
+// purl returns the package URL of the package with the generic type.
+func purl(p *anise_pkg.DefaultPackage) string {
+	return fmt.Sprintf("pkg:generic/%s/%s@%s", p.GetCategory(), p.GetName(),
+		strings.ReplaceAll(p.GetVersion(), "+", "%2B"))
+}
+
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	// Version 4, variant RFC 4122
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// getSBOMPackages returns a package for every artifact of the repository
+// sorted by name.
+// The requires are resolved with the newest package that satisfies them.
+func (c *RepoKnife) getSBOMPackages() []*sbomPackage {
+	ans := []*sbomPackage{}
+	visited := make(map[string]bool, 0)
+	ids := make(map[string]bool, 0)
+
+	for _, m := range c.sortedMetas() {
+		art := c.MetaMap[m]
+		p := artifactPackage(art)
+		if p == nil || visited[p.HumanReadableString()] {
+			continue
+		}
+		visited[p.HumanReadableString()] = true
+
+		id := sbomID(fmt.Sprintf("%s-%s-%s", p.GetCategory(), p.GetName(), p.GetVersion()))
+		for i := 1; ids[id]; i++ {
+			id = sbomID(fmt.Sprintf("%s-%s-%s-%d", p.GetCategory(), p.GetName(),
+				p.GetVersion(), i))
+		}
+		ids[id] = true
+
+		ans = append(ans, &sbomPackage{
+			ID:       id,
+			Package:  p,
+			Artifact: art,
+			Tarball:  filepath.Base(art.Path),
+			Requires: []string{},
+		})
+	}
+
+	sort.Slice(ans, func(i, j int) bool {
+		return ans[i].Package.HumanReadableString() < ans[j].Package.HumanReadableString()
+	})
+
+	for _, sp := range ans {
+		for _, req := range sp.Package.GetRequires() {
+			var dep *sbomPackage
+			for _, candidate := range ans {
+				if !packageSatisfies(req, candidate.Package) {
+					continue
+				}
+				if dep == nil || isNewerVersion(candidate.Package.GetVersion(),
+					dep.Package.GetVersion()) {
+					dep = candidate
+				}
+			}
+
+			if dep == nil {
+				DebugC(fmt.Sprintf("[%s] Requirement %s/%s not available.",
+					sp.Package.HumanReadableString(), req.GetCategory(), req.GetName()))
+				continue
+			}
+			sp.Requires = append(sp.Requires, dep.ID)
+		}
+	}
+
+	return ans
+}
+
+// GetSBOM returns the software bill of materials of the artifacts of
+// the repository in the SPDX 2.3 or CycloneDX 1.5 format. The returned
+// document is ready to be encoded in JSON.
+func (c *RepoKnife) GetSBOM(ctx context.Context, opts SBOMOptions) (interface{}, error) {
+	if opts.Name == "" {
+		opts.Name = "anise-repository"
+	}
+	if opts.Created.IsZero() {
+		opts.Created = time.Now()
+	}
+
+	if opts.Format != SBOMFormatSPDX && opts.Format != SBOMFormatCycloneDX {
+		return nil, errors.New(fmt.Sprintf("Invalid SBOM format %s", opts.Format))
+	}
+
+	// SPDX requires the SHA1 checksum of every file of a package with
+	// the files analyzed, but the metadata of the artifacts don't
+	// contain the checksums of the files.
+	if opts.WithFiles && opts.Format == SBOMFormatSPDX {
+		return nil, errors.New("The files are supported only with the CycloneDX format")
+	}
+
+	err := c.LoadMetadata(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	pkgs := c.getSBOMPackages()
+	if opts.Format == SBOMFormatSPDX {
+		return newSPDXDocument(pkgs, opts)
+	}
+	return newCycloneDXBom(pkgs, opts)
+}
@@ -0,0 +1,178 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+type CycloneDXBom struct {
+	BomFormat    string                `json:"bomFormat"`
+	SpecVersion  string                `json:"specVersion"`
+	SerialNumber string                `json:"serialNumber"`
+	Version      int                   `json:"version"`
+	Metadata     CycloneDXMetadata     `json:"metadata"`
+	Components   []CycloneDXComponent  `json:"components"`
+	Dependencies []CycloneDXDependency `json:"dependencies"`
+}
+
+type CycloneDXMetadata struct {
+	Timestamp string              `json:"timestamp"`
+	Tools     []CycloneDXTool     `json:"tools"`
+	Component *CycloneDXComponent `json:"component,omitempty"`
+}
+
+type CycloneDXTool struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type CycloneDXHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+// CycloneDXLicense is a license of a component or a license expression.
+// An expression is used alone in the licenses of the component.
+type CycloneDXLicense struct {
+	License    *CycloneDXLicenseName `json:"license,omitempty"`
+	Expression string                `json:"expression,omitempty"`
+}
+
+type CycloneDXLicenseName struct {
+	Name string `json:"name"`
+}
+
+type CycloneDXExternalRef struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type CycloneDXComponent struct {
+	Type               string                 `json:"type"`
+	BomRef             string                 `json:"bom-ref,omitempty"`
+	Group              string                 `json:"group,omitempty"`
+	Name               string                 `json:"name"`
+	Version            string                 `json:"version,omitempty"`
+	Description        string                 `json:"description,omitempty"`
+	Licenses           []CycloneDXLicense     `json:"licenses,omitempty"`
+	Hashes             []CycloneDXHash        `json:"hashes,omitempty"`
+	Purl               string                 `json:"purl,omitempty"`
+	ExternalReferences []CycloneDXExternalRef `json:"externalReferences,omitempty"`
+	Components         []CycloneDXComponent   `json:"components,omitempty"`
+}
+
+type CycloneDXDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// cycloneDXHashAlg returns the CycloneDX name of the hash algorithm.
+func cycloneDXHashAlg(alg string) string {
+	alg = strings.ToUpper(alg)
+	if strings.HasPrefix(alg, "SHA") && !strings.HasPrefix(alg, "SHA-") &&
+		!strings.HasPrefix(alg, "SHA3") {
+		alg = "SHA-" + strings.TrimPrefix(alg, "SHA")
+	}
+	return alg
+}
+
+func newCycloneDXBom(pkgs []*sbomPackage, opts SBOMOptions) (*CycloneDXBom, error) {
+	uuid, err := newUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	ans := &CycloneDXBom{
+		BomFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + uuid,
+		Version:      1,
+		Metadata: CycloneDXMetadata{
+			Timestamp: opts.Created.UTC().Format(time.RFC3339),
+			Tools: []CycloneDXTool{{
+				Name:    "anise-repo-devkit",
+				Version: Version,
+			}},
+			Component: &CycloneDXComponent{
+				Type: "operating-system",
+				Name: opts.Name,
+			},
+		},
+		Components:   []CycloneDXComponent{},
+		Dependencies: []CycloneDXDependency{},
+	}
+
+	for _, sp := range pkgs {
+		p := sp.Package
+
+		component := CycloneDXComponent{
+			Type:        "application",
+			BomRef:      sp.ID,
+			Group:       p.GetCategory(),
+			Name:        p.GetName(),
+			Version:     p.GetVersion(),
+			Description: p.GetDescription(),
+			Purl:        purl(p),
+		}
+
+		if license := artifactLicense(p); license != "" {
+			node := parseLicense(license)
+			if node.HasOr() {
+				// A list of licenses means that all licenses apply.
+				component.Licenses = []CycloneDXLicense{{
+					Expression: spdxLicenseExpression(node, true),
+				}}
+			} else {
+				visited := make(map[string]bool, 0)
+				for _, l := range node.Licenses() {
+					if visited[l] {
+						continue
+					}
+					visited[l] = true
+					component.Licenses = append(component.Licenses, CycloneDXLicense{
+						License: &CycloneDXLicenseName{Name: l},
+					})
+				}
+			}
+		}
+
+		algs := []string{}
+		for alg := range sp.Artifact.Checksums {
+			algs = append(algs, alg)
+		}
+		sort.Strings(algs)
+		for _, alg := range algs {
+			component.Hashes = append(component.Hashes, CycloneDXHash{
+				Alg:     cycloneDXHashAlg(alg),
+				Content: sp.Artifact.Checksums[alg],
+			})
+		}
+
+		for _, u := range p.Uri {
+			component.ExternalReferences = append(component.ExternalReferences,
+				CycloneDXExternalRef{Type: "website", URL: u})
+		}
+
+		if opts.WithFiles {
+			for _, f := range sp.Artifact.Files {
+				component.Components = append(component.Components, CycloneDXComponent{
+					Type: "file",
+					Name: "/" + strings.TrimPrefix(f, "/"),
+				})
+			}
+		}
+
+		ans.Components = append(ans.Components, component)
+		ans.Dependencies = append(ans.Dependencies, CycloneDXDependency{
+			Ref:       sp.ID,
+			DependsOn: sp.Requires,
+		})
+	}
+
+	return ans, nil
+}
@@ -0,0 +1,217 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const spdxNoAssertion = "NOASSERTION"
+
+type SPDXDocument struct {
+	SPDXVersion       string                     `json:"spdxVersion"`
+	DataLicense       string                     `json:"dataLicense"`
+	SPDXID            string                     `json:"SPDXID"`
+	Name              string                     `json:"name"`
+	DocumentNamespace string                     `json:"documentNamespace"`
+	CreationInfo      SPDXCreationInfo           `json:"creationInfo"`
+	Packages          []SPDXPackage              `json:"packages"`
+	Relationships     []SPDXRelationship         `json:"relationships"`
+	ExtractedLicenses []SPDXExtractedLicenseInfo `json:"hasExtractedLicensingInfos,omitempty"`
+}
+
+type SPDXCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type SPDXChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type SPDXExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type SPDXPackage struct {
+	Name             string            `json:"name"`
+	SPDXID           string            `json:"SPDXID"`
+	VersionInfo      string            `json:"versionInfo"`
+	PackageFileName  string            `json:"packageFileName,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	Homepage         string            `json:"homepage,omitempty"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	LicenseConcluded string            `json:"licenseConcluded"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	CopyrightText    string            `json:"copyrightText"`
+	Description      string            `json:"description,omitempty"`
+	Checksums        []SPDXChecksum    `json:"checksums,omitempty"`
+	ExternalRefs     []SPDXExternalRef `json:"externalRefs,omitempty"`
+}
+
+type SPDXRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+type SPDXExtractedLicenseInfo struct {
+	LicenseID     string `json:"licenseId"`
+	Name          string `json:"name"`
+	ExtractedText string `json:"extractedText"`
+}
+
+// spdxLicenseRef returns the SPDX reference of a license of the
+// package metadata. The licenses of the metadata aren't SPDX
+// identifiers and they are exported as LicenseRef.
+func spdxLicenseRef(l string) string {
+	return "LicenseRef-" + sbomID(l)
+}
+
+// spdxLicenseExpression converts a license expression of the package
+// metadata to a SPDX license expression.
+func spdxLicenseExpression(n *licenseNode, top bool) string {
+	if n.License != "" {
+		return spdxLicenseRef(n.License)
+	}
+
+	children := []string{}
+	for _, c := range n.Children {
+		if s := spdxLicenseExpression(c, false); s != "" {
+			children = append(children, s)
+		}
+	}
+	if len(children) == 0 {
+		return ""
+	}
+
+	op := " AND "
+	if n.Or {
+		op = " OR "
+	}
+	ans := strings.Join(children, op)
+	if !top && len(children) > 1 {
+		ans = "(" + ans + ")"
+	}
+	return ans
+}
+
+func newSPDXDocument(pkgs []*sbomPackage, opts SBOMOptions) (*SPDXDocument, error) {
+	uuid, err := newUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	ans := &SPDXDocument{
+		SPDXVersion: "SPDX-2.3",
+		DataLicense: "CC0-1.0",
+		SPDXID:      "SPDXRef-DOCUMENT",
+		Name:        opts.Name,
+		DocumentNamespace: fmt.Sprintf("https://macaroni.funtoo.org/spdxdocs/%s-%s",
+			sbomID(opts.Name), uuid),
+		CreationInfo: SPDXCreationInfo{
+			Created:  opts.Created.UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: anise-repo-devkit-" + Version},
+		},
+		Packages:      []SPDXPackage{},
+		Relationships: []SPDXRelationship{},
+	}
+
+	licenses := make(map[string]bool, 0)
+
+	for _, sp := range pkgs {
+		p := sp.Package
+		id := "SPDXRef-Package-" + sp.ID
+
+		spdxPkg := SPDXPackage{
+			Name:             p.GetCategory() + "/" + p.GetName(),
+			SPDXID:           id,
+			VersionInfo:      p.GetVersion(),
+			PackageFileName:  sp.Tarball,
+			DownloadLocation: spdxNoAssertion,
+			FilesAnalyzed:    false,
+			LicenseConcluded: spdxNoAssertion,
+			LicenseDeclared:  spdxNoAssertion,
+			CopyrightText:    spdxNoAssertion,
+			Description:      p.GetDescription(),
+			Checksums:        []SPDXChecksum{},
+			ExternalRefs: []SPDXExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  purl(p),
+			}},
+		}
+
+		if license := artifactLicense(p); license != "" {
+			node := parseLicense(license)
+			if expr := spdxLicenseExpression(node, true); expr != "" {
+				spdxPkg.LicenseDeclared = expr
+				for _, l := range node.Licenses() {
+					licenses[l] = true
+				}
+			}
+		}
+
+		for i, u := range p.Uri {
+			if i == 0 {
+				spdxPkg.Homepage = u
+				continue
+			}
+			spdxPkg.ExternalRefs = append(spdxPkg.ExternalRefs, SPDXExternalRef{
+				ReferenceCategory: "OTHER",
+				ReferenceType:     "url",
+				ReferenceLocator:  u,
+			})
+		}
+
+		algs := []string{}
+		for alg := range sp.Artifact.Checksums {
+			algs = append(algs, alg)
+		}
+		sort.Strings(algs)
+		for _, alg := range algs {
+			spdxPkg.Checksums = append(spdxPkg.Checksums, SPDXChecksum{
+				Algorithm:     strings.ToUpper(alg),
+				ChecksumValue: sp.Artifact.Checksums[alg],
+			})
+		}
+
+		ans.Packages = append(ans.Packages, spdxPkg)
+		ans.Relationships = append(ans.Relationships, SPDXRelationship{
+			SPDXElementID:      ans.SPDXID,
+			RelationshipType:   "DESCRIBES",
+			RelatedSPDXElement: id,
+		})
+
+		for _, r := range sp.Requires {
+			ans.Relationships = append(ans.Relationships, SPDXRelationship{
+				SPDXElementID:      id,
+				RelationshipType:   "DEPENDS_ON",
+				RelatedSPDXElement: "SPDXRef-Package-" + r,
+			})
+		}
+	}
+
+	names := []string{}
+	for l := range licenses {
+		names = append(names, l)
+	}
+	sort.Strings(names)
+	for _, l := range names {
+		ans.ExtractedLicenses = append(ans.ExtractedLicenses, SPDXExtractedLicenseInfo{
+			LicenseID:     spdxLicenseRef(l),
+			Name:          l,
+			ExtractedText: fmt.Sprintf("The license %s of the package metadata.", l),
+		})
+	}
+
+	return ans, nil
+}
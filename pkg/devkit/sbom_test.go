@@ -0,0 +1,168 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	specs "github.com/macaroni-os/anise-repo-devkit/pkg/specs"
+
+	anise_pkg "github.com/geaaru/luet/pkg/package"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+var sbomFixtures = []*fixture{
+	{Category: "app", Name: "foo", Version: "1.0", Compression: "zst",
+		License: "|| ( GPL-2 MIT ) BSD",
+		Requires: []*anise_pkg.DefaultPackage{
+			{Category: "lib", Name: "bar", Version: ">=1.0"},
+			{Category: "lib", Name: "missing", Version: ">=1.0"},
+		},
+		Files:  []string{"usr/bin/foo"},
+		InTree: true, WithMeta: true, WithTarball: true},
+	{Category: "lib", Name: "bar", Version: "1.0", Compression: "zst",
+		License: "GPL-2 BSD",
+		Files:   []string{"usr/lib/libbar.so"},
+		InTree:  true, WithMeta: true, WithTarball: true},
+	{Category: "lib", Name: "bar", Version: "1.1+git", Compression: "zst",
+		Files:  []string{"usr/lib/libbar.so"},
+		InTree: true, WithMeta: true, WithTarball: true},
+}
+
+// validateSBOMSchema validates the JSON encoding of the document with
+// a schema of the testdata directory.
+func validateSBOMSchema(t *testing.T, schema string, doc interface{}) {
+	t.Helper()
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := filepath.Abs(filepath.Join("testdata", schema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := gojsonschema.Validate(
+		gojsonschema.NewReferenceLoader("file://"+filepath.ToSlash(path)),
+		gojsonschema.NewBytesLoader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range res.Errors() {
+		t.Errorf("Invalid document: %s", e.String())
+	}
+}
+
+func TestSPDXDocument(t *testing.T) {
+	s := specs.NewAniseRDConfig()
+	knife, _ := newFixtureKnife(t, s, sbomFixtures)
+
+	doc, err := knife.GetSBOM(context.Background(), SBOMOptions{
+		Format: SBOMFormatSPDX,
+		Name:   "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spdx := doc.(*SPDXDocument)
+	validateSBOMSchema(t, "spdx-2.3.schema.json", spdx)
+
+	ids := []string{}
+	elements := map[string]bool{spdx.SPDXID: true}
+	for _, p := range spdx.Packages {
+		ids = append(ids, p.SPDXID)
+		elements[p.SPDXID] = true
+		if p.SPDXID == "SPDXRef-Package-app-foo-1.0" &&
+			p.LicenseDeclared != "(LicenseRef-GPL-2 OR LicenseRef-MIT) AND LicenseRef-BSD" {
+			t.Errorf("Unexpected license %s", p.LicenseDeclared)
+		}
+	}
+	assertFiles(t, ids,
+		"SPDXRef-Package-app-foo-1.0",
+		"SPDXRef-Package-lib-bar-1.0",
+		"SPDXRef-Package-lib-bar-1.1-git",
+	)
+
+	relations := []string{}
+	for _, r := range spdx.Relationships {
+		if !elements[r.SPDXElementID] || !elements[r.RelatedSPDXElement] {
+			t.Errorf("Relationship with an undefined element: %+v", r)
+		}
+		if r.RelationshipType != "DESCRIBES" {
+			relations = append(relations, strings.Join([]string{
+				r.SPDXElementID, r.RelationshipType, r.RelatedSPDXElement}, " "))
+		}
+	}
+	assertFiles(t, relations,
+		"SPDXRef-Package-app-foo-1.0 DEPENDS_ON SPDXRef-Package-lib-bar-1.1-git",
+	)
+
+	if len(spdx.ExtractedLicenses) != 3 {
+		t.Errorf("Unexpected extracted licenses: %v", spdx.ExtractedLicenses)
+	}
+
+	// The files of the packages without checksums aren't valid in SPDX.
+	_, err = knife.GetSBOM(context.Background(), SBOMOptions{
+		Format:    SBOMFormatSPDX,
+		WithFiles: true,
+	})
+	if err == nil {
+		t.Error("Expected error with the files in SPDX")
+	}
+}
+
+func TestCycloneDXBom(t *testing.T) {
+	s := specs.NewAniseRDConfig()
+	knife, _ := newFixtureKnife(t, s, sbomFixtures)
+
+	doc, err := knife.GetSBOM(context.Background(), SBOMOptions{
+		Format:    SBOMFormatCycloneDX,
+		WithFiles: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bom := doc.(*CycloneDXBom)
+	validateSBOMSchema(t, "cyclonedx-1.5.schema.json", bom)
+
+	if len(bom.Components) != 3 {
+		t.Fatalf("Unexpected components: %v", bom.Components)
+	}
+
+	// A license choice is exported as expression.
+	foo := bom.Components[0]
+	if foo.Purl != "pkg:generic/app/foo@1.0" || len(foo.Licenses) != 1 ||
+		foo.Licenses[0].Expression != "(LicenseRef-GPL-2 OR LicenseRef-MIT) AND LicenseRef-BSD" ||
+		len(foo.Components) != 1 {
+		t.Errorf("Unexpected component: %+v", foo)
+	}
+
+	// All licenses apply.
+	bar := bom.Components[1]
+	if len(bar.Licenses) != 2 || bar.Licenses[0].License == nil ||
+		bar.Licenses[0].License.Name != "GPL-2" || bar.Licenses[1].Expression != "" {
+		t.Errorf("Unexpected component: %+v", bar)
+	}
+
+	deps := []string{}
+	for _, d := range bom.Dependencies {
+		deps = append(deps, d.Ref+" "+strings.Join(d.DependsOn, ","))
+	}
+	assertFiles(t, deps,
+		"app-foo-1.0 lib-bar-1.1-git",
+		"lib-bar-1.0 ",
+		"lib-bar-1.1-git ",
+	)
+
+	if _, err := knife.GetSBOM(context.Background(), SBOMOptions{Format: "xml"}); err == nil {
+		t.Error("Expected error with an invalid format")
+	}
+}
@@ -0,0 +1,158 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+
+	specs "github.com/macaroni-os/anise-repo-devkit/pkg/specs"
+
+	. "github.com/geaaru/luet/pkg/logger"
+)
+
+// CategoryStats contains the statistics of a category of the repository.
+type CategoryStats struct {
+	Category  string `json:"category"`
+	Packages  int    `json:"packages"`
+	Artifacts int    `json:"artifacts"`
+	Size      int64  `json:"size"`
+}
+
+// ArtifactSize describes the size of the tarball of an artifact.
+type ArtifactSize struct {
+	Package string `json:"package"`
+	Path    string `json:"path"`
+	Size    int64  `json:"size"`
+}
+
+// BuildPeriodStats contains the number of artifacts built in a month.
+type BuildPeriodStats struct {
+	// The month in the format YYYY-MM or unknown.
+	Period    string `json:"period"`
+	Artifacts int    `json:"artifacts"`
+}
+
+// RepoStats contains the statistics of the artifacts of the repository.
+type RepoStats struct {
+	Packages  int   `json:"packages"`
+	Artifacts int   `json:"artifacts"`
+	Size      int64 `json:"size"`
+	// False if the backend doesn't return the size of the files. In
+	// this case all sizes are zero and the largest artifacts are empty.
+	SizeAvailable bool `json:"size_available"`
+	// Number of packages with more than one version.
+	MultiVersionPackages int                `json:"multi_version_packages"`
+	Categories           []CategoryStats    `json:"categories"`
+	Largest              []ArtifactSize     `json:"largest"`
+	Compressions         map[string]int     `json:"compressions"`
+	BuildPeriods         []BuildPeriodStats `json:"build_periods"`
+}
+
+// GetStats returns the statistics of the artifacts of the repository.
+// The size of an artifact is the size of its tarball and of its metadata
+// file. The top argument is the number of largest artifacts returned.
+func (c *RepoKnife) GetStats(ctx context.Context, top int) (*RepoStats, error) {
+	err := c.LoadMetadata(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ans := &RepoStats{
+		Categories:   []CategoryStats{},
+		Largest:      []ArtifactSize{},
+		Compressions: make(map[string]int, 0),
+		BuildPeriods: []BuildPeriodStats{},
+	}
+
+	_, ans.SizeAvailable = c.BackendHandler.(specs.RepoBackendFilesInfo)
+	if !ans.SizeAvailable {
+		Warning("The backend doesn't return the size of the files. " +
+			"The sizes are not available.")
+	}
+
+	mCategories := make(map[string]*CategoryStats, 0)
+	mVersions := make(map[string]map[string]bool, 0)
+	mPeriods := make(map[string]int, 0)
+
+	for _, m := range c.sortedMetas() {
+		art := c.MetaMap[m]
+		p := artifactPackage(art)
+		if p == nil {
+			continue
+		}
+
+		tarball := filepath.Base(art.Path)
+		size := c.FilesSize[tarball] + c.FilesSize[m]
+
+		cat, ok := mCategories[p.GetCategory()]
+		if !ok {
+			cat = &CategoryStats{Category: p.GetCategory()}
+			mCategories[p.GetCategory()] = cat
+		}
+		cat.Artifacts++
+		cat.Size += size
+
+		if _, ok := mVersions[p.GetPackageName()]; !ok {
+			mVersions[p.GetPackageName()] = make(map[string]bool, 0)
+			cat.Packages++
+		}
+		mVersions[p.GetPackageName()][p.GetVersion()] = true
+
+		compression := string(art.CompressionType)
+		if compression == "" {
+			compression = "none"
+		}
+		ans.Compressions[compression]++
+
+		period := "unknown"
+		if t := artifactBuildTime(art); !t.IsZero() {
+			period = t.UTC().Format("2006-01")
+		}
+		mPeriods[period]++
+
+		ans.Artifacts++
+		ans.Size += size
+		if ans.SizeAvailable {
+			ans.Largest = append(ans.Largest, ArtifactSize{
+				Package: p.HumanReadableString(),
+				Path:    tarball,
+				Size:    c.FilesSize[tarball],
+			})
+		}
+	}
+
+	ans.Packages = len(mVersions)
+	for _, versions := range mVersions {
+		if len(versions) > 1 {
+			ans.MultiVersionPackages++
+		}
+	}
+
+	for _, cat := range mCategories {
+		ans.Categories = append(ans.Categories, *cat)
+	}
+	sort.Slice(ans.Categories, func(i, j int) bool {
+		return ans.Categories[i].Category < ans.Categories[j].Category
+	})
+
+	for period, n := range mPeriods {
+		ans.BuildPeriods = append(ans.BuildPeriods,
+			BuildPeriodStats{Period: period, Artifacts: n})
+	}
+	sort.Slice(ans.BuildPeriods, func(i, j int) bool {
+		return ans.BuildPeriods[i].Period < ans.BuildPeriods[j].Period
+	})
+
+	sort.SliceStable(ans.Largest, func(i, j int) bool {
+		return ans.Largest[i].Size > ans.Largest[j].Size
+	})
+	if top >= 0 && len(ans.Largest) > top {
+		ans.Largest = ans.Largest[:top]
+	}
+
+	return ans, nil
+}
@@ -0,0 +1,106 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"context"
+	"testing"
+
+	specs "github.com/macaroni-os/anise-repo-devkit/pkg/specs"
+)
+
+var statsFixtures = []*fixture{
+	{Category: "app", Name: "foo", Version: "1.0", Compression: "zst",
+		BuildTimestamp: "1735732800",
+		InTree:         true, WithMeta: true, WithTarball: true},
+	{Category: "app", Name: "foo", Version: "1.1", Compression: "zst",
+		BuildTimestamp: "1738411200",
+		InTree:         true, WithMeta: true, WithTarball: true},
+	{Category: "app", Name: "bar", Version: "1.0", Compression: "gz",
+		BuildTimestamp: "1738411200",
+		InTree:         true, WithMeta: true, WithTarball: true},
+	{Category: "lib", Name: "baz", Version: "2.0",
+		InTree: true, WithMeta: true, WithTarball: true},
+}
+
+func TestGetStats(t *testing.T) {
+	s := specs.NewAniseRDConfig()
+	knife, b := newFixtureKnife(t, s, statsFixtures)
+
+	for i, f := range statsFixtures {
+		b.AddFile(f.Tarball(), make([]byte, (i+1)*100))
+	}
+
+	stats, err := knife.GetStats(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !stats.SizeAvailable {
+		t.Error("Expected sizes available")
+	}
+
+	if stats.Packages != 3 || stats.Artifacts != 4 || stats.MultiVersionPackages != 1 {
+		t.Errorf("Unexpected counters: %d packages, %d artifacts, %d multi versions",
+			stats.Packages, stats.Artifacts, stats.MultiVersionPackages)
+	}
+
+	if len(stats.Categories) != 2 ||
+		stats.Categories[0].Category != "app" || stats.Categories[0].Packages != 2 ||
+		stats.Categories[0].Artifacts != 3 ||
+		stats.Categories[1].Category != "lib" || stats.Categories[1].Packages != 1 {
+		t.Errorf("Unexpected categories: %v", stats.Categories)
+	}
+
+	var catSize int64
+	for _, c := range stats.Categories {
+		catSize += c.Size
+	}
+	if stats.Size != catSize || stats.Size < 1000 {
+		t.Errorf("Unexpected size %d (categories %d)", stats.Size, catSize)
+	}
+
+	if len(stats.Largest) != 2 ||
+		stats.Largest[0].Package != "lib/baz-2.0" || stats.Largest[0].Size != 400 ||
+		stats.Largest[1].Package != "app/bar-1.0" || stats.Largest[1].Size != 300 {
+		t.Errorf("Unexpected largest artifacts: %v", stats.Largest)
+	}
+
+	if stats.Compressions["zstd"] != 2 || stats.Compressions["gzip"] != 1 ||
+		stats.Compressions["none"] != 1 {
+		t.Errorf("Unexpected compressions: %v", stats.Compressions)
+	}
+
+	expected := []BuildPeriodStats{
+		{Period: "2025-01", Artifacts: 1},
+		{Period: "2025-02", Artifacts: 2},
+		{Period: "unknown", Artifacts: 1},
+	}
+	if len(stats.BuildPeriods) != len(expected) {
+		t.Fatalf("Unexpected build periods: %v", stats.BuildPeriods)
+	}
+	for i := range expected {
+		if stats.BuildPeriods[i] != expected[i] {
+			t.Errorf("Unexpected build periods: %v", stats.BuildPeriods)
+		}
+	}
+}
+
+func TestGetStatsWithoutSizes(t *testing.T) {
+	s := specs.NewAniseRDConfig()
+	b := newFixtureBackend(t, s, statsFixtures)
+
+	// A backend without the files information.
+	knife := NewRepoKnifeWithBackend(s, struct{ specs.RepoBackendHandler }{b})
+	stats, err := knife.GetStats(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.SizeAvailable || stats.Size != 0 || len(stats.Largest) != 0 ||
+		stats.Artifacts != 4 {
+		t.Fatalf("Unexpected stats without sizes: %+v", stats)
+	}
+}
@@ -0,0 +1,456 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	specs "github.com/macaroni-os/anise-repo-devkit/pkg/specs"
+
+	. "github.com/geaaru/luet/pkg/logger"
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	TreeKindLocal   = "local"
+	TreeKindGit     = "git"
+	TreeKindTarball = "tarball"
+)
+
+// TreeSource describes a tree loaded from a local directory, a git
+// repository or a tarball.
+type TreeSource struct {
+	Source string `json:"source" yaml:"source"`
+	Kind   string `json:"kind" yaml:"kind"`
+	// Git reference (branch, tag or commit) of the git trees.
+	Ref string `json:"ref,omitempty" yaml:"ref,omitempty"`
+	// Local path of the tree.
+	Path string `json:"path" yaml:"path"`
+	// Commit of the git trees or sha256 of the tarballs.
+	Revision string `json:"revision,omitempty" yaml:"revision,omitempty"`
+}
+
+var (
+	gitSourceRegex     = regexp.MustCompile(`^(git\+|git://|ssh://|git@)|\.git$`)
+	tarballSourceRegex = regexp.MustCompile(`\.(tar|tar\.gz|tgz|tar\.zst|tar\.zstd)$`)
+
+	// The trees published in the repository ordered by preference.
+	runtimeTreeFiles = []string{"tree.tar.zst", "tree.tar.gz", "tree.tar"}
+	buildTreeFiles   = []string{
+		"compilertree.tar.zst", "compilertree.tar.gz", "compilertree.tar",
+	}
+)
+
+// DefaultTreesCacheDir returns the directory where the git and the
+// tarball trees are checked out.
+func DefaultTreesCacheDir() string {
+	return filepath.Join(DefaultCacheDir(), "trees")
+}
+
+// ParseGitTreeSource returns the URL and the optional reference of a
+// git tree in the format [git+]url[#ref].
+func ParseGitTreeSource(s string) (string, string) {
+	url, ref, _ := strings.Cut(strings.TrimPrefix(s, "git+"), "#")
+	return url, ref
+}
+
+// IsGitTreeSource returns true if the source is a git URL. The URLs
+// with the git+ prefix, the git and ssh schemes and the URLs ending
+// with .git are git trees.
+func IsGitTreeSource(s string) bool {
+	url, _ := ParseGitTreeSource(s)
+	return strings.HasPrefix(s, "git+") || gitSourceRegex.MatchString(url)
+}
+
+// IsTarballTreeSource returns true if the source is a tar archive
+// optionally compressed with gzip or zstd.
+func IsTarballTreeSource(s string) bool {
+	return tarballSourceRegex.MatchString(s)
+}
+
+func cacheKey(s string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(s)))[:16]
+}
+
+// ResolveTrees resolves the sources of the trees. The git trees and the
+// tarballs are checked out or extracted in the cache directory.
+func ResolveTrees(ctx context.Context, sources []string, cacheDir string) ([]*TreeSource, error) {
+	ans := []*TreeSource{}
+	for _, s := range sources {
+		t, err := ResolveTree(ctx, s, cacheDir)
+		if err != nil {
+			return ans, err
+		}
+		ans = append(ans, t)
+	}
+	return ans, nil
+}
+
+// ResolveTree resolves the source of a tree: a local directory, a git
+// URL with an optional #ref or a tree tarball.
+func ResolveTree(ctx context.Context, source, cacheDir string) (*TreeSource, error) {
+	var err error
+	ans := &TreeSource{Source: source}
+
+	switch {
+	case IsGitTreeSource(source):
+		url, ref := ParseGitTreeSource(source)
+		ans.Kind = TreeKindGit
+		ans.Ref = ref
+		// Every reference has its own checkout because the trees
+		// are all resolved before they are loaded.
+		ans.Path = filepath.Join(cacheDir, "git-"+cacheKey(url+"#"+ref))
+		ans.Revision, err = checkoutGitTree(ctx, url, ref, ans.Path)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Error on checkout tree %s: %s",
+				source, err.Error()))
+		}
+
+	case IsTarballTreeSource(source):
+		ans.Kind = TreeKindTarball
+		ans.Path, ans.Revision, err = extractTreeTarball(source, cacheDir)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Error on extract tree %s: %s",
+				source, err.Error()))
+		}
+
+	default:
+		ans.Kind = TreeKindLocal
+		ans.Path = source
+		ans.Revision = GetGitRevision(source)
+	}
+
+	DebugC(fmt.Sprintf("Tree %s resolved to %s (%s).", source, ans.Path, ans.Revision))
+	return ans, nil
+}
+
+// FetchRepositoryTrees downloads the runtime tree (tree.tar) and the build
+// tree (compilertree.tar) published in the repository and extracts them
+// in the cache directory. If only one of them is published it's used as
+// runtime and build tree.
+func (c *RepoKnife) FetchRepositoryTrees(ctx context.Context,
+	cacheDir string) (*TreeSource, *TreeSource, error) {
+
+	downloader, ok := c.BackendHandler.(specs.RepoBackendDownloader)
+	if !ok {
+		return nil, nil, errors.New("The backend doesn't support the download of the trees")
+	}
+
+	files, err := c.BackendHandler.GetFilesList(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	mFiles := make(map[string]bool, len(files))
+	for _, f := range files {
+		mFiles[f] = true
+	}
+
+	fetch := func(candidates []string) (*TreeSource, error) {
+		for _, f := range candidates {
+			if mFiles[f] {
+				return fetchRepositoryTree(ctx, downloader, f, cacheDir)
+			}
+		}
+		return nil, nil
+	}
+
+	runtime, err := fetch(runtimeTreeFiles)
+	if err != nil {
+		return nil, nil, err
+	}
+	build, err := fetch(buildTreeFiles)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if runtime == nil && build == nil {
+		return nil, nil, errors.New("No trees published in the repository")
+	} else if runtime == nil {
+		runtime = build
+	} else if build == nil {
+		build = runtime
+	}
+
+	return runtime, build, nil
+}
+
+func fetchRepositoryTree(ctx context.Context, downloader specs.RepoBackendDownloader,
+	file, cacheDir string) (*TreeSource, error) {
+
+	InfoC(fmt.Sprintf(":evergreen_tree: Downloading tree %s...", file))
+
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return nil, err
+	}
+
+	// Keep the extension of the file to detect the compression.
+	tmpFile, err := os.CreateTemp(cacheDir, "download-*-"+file)
+	if err != nil {
+		return nil, err
+	}
+	tmpFile.Close()
+	defer os.Remove(tmpFile.Name())
+
+	err = downloader.DownloadFile(ctx, file, tmpFile.Name())
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error on download tree %s: %s",
+			file, err.Error()))
+	}
+
+	ans := &TreeSource{
+		Source: "repository:" + file,
+		Kind:   TreeKindTarball,
+	}
+	ans.Path, ans.Revision, err = extractTreeTarball(tmpFile.Name(), cacheDir)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error on extract tree %s: %s",
+			file, err.Error()))
+	}
+
+	return ans, nil
+}
+
+// GetTreesPaths returns the local paths of the trees.
+func GetTreesPaths(trees []*TreeSource) []string {
+	ans := []string{}
+	for _, t := range trees {
+		ans = append(ans, t.Path)
+	}
+	return ans
+}
+
+// checkoutGitTree clones or updates the repository in the directory and
+// checks out the reference. Without reference the default branch of the
+// remote is used. It returns the commit checked out.
+func checkoutGitTree(ctx context.Context, url, ref, dir string) (string, error) {
+	repo, err := git.PlainOpen(dir)
+	if err == git.ErrRepositoryNotExists {
+		InfoC(fmt.Sprintf(":evergreen_tree: Cloning tree %s...", url))
+		repo, err = git.PlainCloneContext(ctx, dir, false, &git.CloneOptions{
+			URL:        url,
+			NoCheckout: true,
+		})
+	} else if err == nil {
+		DebugC(fmt.Sprintf(":evergreen_tree: Fetching tree %s...", url))
+		err = repo.FetchContext(ctx, &git.FetchOptions{
+			RemoteName: git.DefaultRemoteName,
+			RefSpecs: []config.RefSpec{
+				"+refs/heads/*:refs/remotes/origin/*",
+			},
+			Tags:  git.AllTags,
+			Force: true,
+		})
+		if err == git.NoErrAlreadyUpToDate {
+			err = nil
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	hash, err := resolveGitRef(repo, ref)
+	if err != nil {
+		return "", err
+	}
+
+	w, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+
+	err = w.Checkout(&git.CheckoutOptions{Hash: hash, Force: true})
+	if err != nil {
+		return "", err
+	}
+
+	return hash.String(), nil
+}
+
+// resolveGitRef returns the commit of a branch, a tag or a commit hash.
+func resolveGitRef(repo *git.Repository, ref string) (plumbing.Hash, error) {
+	if ref == "" {
+		remote, err := repo.Remote(git.DefaultRemoteName)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		refs, err := remote.List(&git.ListOptions{})
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		for _, r := range refs {
+			if r.Name() != plumbing.HEAD {
+				continue
+			}
+			if r.Type() == plumbing.HashReference {
+				return r.Hash(), nil
+			}
+			ref = r.Target().Short()
+		}
+		if ref == "" {
+			return plumbing.ZeroHash, errors.New("Default branch not found")
+		}
+	}
+
+	for _, candidate := range []string{
+		"refs/remotes/" + git.DefaultRemoteName + "/" + ref,
+		"refs/tags/" + ref,
+		ref,
+	} {
+		h, err := repo.ResolveRevision(plumbing.Revision(candidate))
+		if err != nil {
+			continue
+		}
+
+		// Peel the annotated tags
+		if tag, err := repo.TagObject(*h); err == nil {
+			c, err := tag.Commit()
+			if err != nil {
+				return plumbing.ZeroHash, err
+			}
+			return c.Hash, nil
+		}
+		return *h, nil
+	}
+
+	return plumbing.ZeroHash, errors.New(fmt.Sprintf("Reference %s not found", ref))
+}
+
+func fileSha256(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// extractTreeTarball extracts the tarball in the cache directory. The
+// tarball is extracted only if the cache doesn't contain it already.
+// It returns the path of the tree and the sha256 of the tarball.
+func extractTreeTarball(file, cacheDir string) (string, string, error) {
+	sum, err := fileSha256(file)
+	if err != nil {
+		return "", "", err
+	}
+
+	dir := filepath.Join(cacheDir, "tarball-"+sum[:16])
+	if _, err = os.Stat(dir); err == nil {
+		return dir, "sha256:" + sum, nil
+	}
+
+	InfoC(fmt.Sprintf(":evergreen_tree: Extracting tree %s...", file))
+
+	if err = os.MkdirAll(cacheDir, 0755); err != nil {
+		return "", "", err
+	}
+
+	// Extract in a temporary directory to avoid partial trees.
+	tmpDir, err := os.MkdirTemp(cacheDir, "tarball-")
+	if err != nil {
+		return "", "", err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err = extractTarball(file, tmpDir); err != nil {
+		return "", "", err
+	}
+
+	if err = os.Rename(tmpDir, dir); err != nil {
+		return "", "", err
+	}
+
+	return dir, "sha256:" + sum, nil
+}
+
+func extractTarball(file, dir string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	switch {
+	case strings.HasSuffix(file, ".gz") || strings.HasSuffix(file, ".tgz"):
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	case strings.HasSuffix(file, ".zst") || strings.HasSuffix(file, ".zstd"):
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		name := filepath.Clean(h.Name)
+		if filepath.IsAbs(name) || name == ".." ||
+			strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return errors.New(fmt.Sprintf("Invalid path %s in the tarball", h.Name))
+		}
+		target := filepath.Join(dir, name)
+
+		switch h.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, 0755)
+		case tar.TypeReg:
+			err = writeTarFile(tr, target, os.FileMode(h.Mode).Perm())
+		default:
+			DebugC(fmt.Sprintf("Ignoring %s of the tarball %s.", h.Name, file))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeTarFile(r io.Reader, target string, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode|0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, r)
+	return err
+}
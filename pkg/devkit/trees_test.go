@@ -0,0 +1,358 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package devkit
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	specs "github.com/macaroni-os/anise-repo-devkit/pkg/specs"
+
+	anise_pkg "github.com/geaaru/luet/pkg/package"
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestTreeSourceKind(t *testing.T) {
+	for s, expected := range map[string]string{
+		"/var/trees/macaroni":                          TreeKindLocal,
+		"https://github.com/macaroni-os/macaroni.git":  TreeKindGit,
+		"git+https://example.org/trees/macaroni#v1.0":  TreeKindGit,
+		"git@github.com:macaroni-os/macaroni.git#main": TreeKindGit,
+		"/var/trees/macaroni.git#develop":              TreeKindGit,
+		"/var/trees/tree.tar":                          TreeKindTarball,
+		"/var/trees/tree.tar.gz":                       TreeKindTarball,
+		"/var/trees/compilertree.tar.zst":              TreeKindTarball,
+	} {
+		kind := TreeKindLocal
+		if IsGitTreeSource(s) {
+			kind = TreeKindGit
+		} else if IsTarballTreeSource(s) {
+			kind = TreeKindTarball
+		}
+		if kind != expected {
+			t.Errorf("Unexpected kind %s for %s", kind, s)
+		}
+	}
+
+	url, ref := ParseGitTreeSource("git+https://example.org/trees/macaroni#v1.0")
+	if url != "https://example.org/trees/macaroni" || ref != "v1.0" {
+		t.Errorf("Unexpected url %s and ref %s", url, ref)
+	}
+}
+
+// commitFixtureTree writes the tree of the fixtures in the worktree
+// and commits it.
+func commitFixtureTree(t *testing.T, repo *git.Repository, dir string,
+	fixtures []*fixture) plumbing.Hash {
+	t.Helper()
+
+	src := newFixtureTree(t, fixtures)
+	err := filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(src, p)
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if err = os.MkdirAll(filepath.Join(dir, filepath.Dir(rel)), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dir, rel), data, 0644)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.AddGlob("."); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := w.Commit("Update tree", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.org", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func assertTreePackages(t *testing.T, tree *TreeSource, expected ...string) {
+	t.Helper()
+
+	knife := NewRepoKnifeWithBackend(specs.NewAniseRDConfig(), nil)
+	if err := knife.LoadTreeSources([]*TreeSource{tree}); err != nil {
+		t.Fatal(err)
+	}
+
+	pkgs := []string{}
+	for _, p := range knife.ReciperRuntime.GetDatabase().World() {
+		pkgs = append(pkgs, p.(*anise_pkg.DefaultPackage).HumanReadableString())
+	}
+	assertFiles(t, pkgs, expected...)
+}
+
+func TestResolveGitTree(t *testing.T) {
+	workDir := t.TempDir()
+	repo, err := git.PlainInit(workDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := commitFixtureTree(t, repo, workDir, []*fixture{
+		{Category: "app", Name: "foo", Version: "1.0", InTree: true},
+	})
+	if _, err = repo.CreateTag("v1", first, nil); err != nil {
+		t.Fatal(err)
+	}
+	second := commitFixtureTree(t, repo, workDir, []*fixture{
+		{Category: "app", Name: "foo", Version: "1.1", InTree: true},
+	})
+
+	bareDir := filepath.Join(t.TempDir(), "tree.git")
+	_, err = git.PlainClone(bareDir, true, &git.CloneOptions{URL: workDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cacheDir := t.TempDir()
+	tree, err := ResolveTree(context.Background(), bareDir+"#v1", cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.Kind != TreeKindGit || tree.Ref != "v1" || tree.Revision != first.String() ||
+		!strings.HasPrefix(tree.Path, cacheDir) {
+		t.Fatalf("Unexpected tree %+v", tree)
+	}
+	assertTreePackages(t, tree, "app/foo-1.0")
+
+	// The cached checkout of the reference is reused.
+	again, err := ResolveTree(context.Background(), bareDir+"#v1", cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Path != tree.Path || again.Revision != first.String() {
+		t.Fatalf("Unexpected tree %+v", again)
+	}
+
+	tree, err = ResolveTree(context.Background(), bareDir, cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.Revision != second.String() {
+		t.Fatalf("Unexpected revision %s, expected %s", tree.Revision, second)
+	}
+	assertTreePackages(t, tree, "app/foo-1.0", "app/foo-1.1")
+
+	if _, err = ResolveTree(context.Background(), bareDir+"#missing", cacheDir); err == nil {
+		t.Error("Expected error with a missing reference")
+	}
+}
+
+func TestResolveGitTreesSameRepository(t *testing.T) {
+	workDir := t.TempDir()
+	repo, err := git.PlainInit(workDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := commitFixtureTree(t, repo, workDir, []*fixture{
+		{Category: "app", Name: "foo", Version: "1.0", InTree: true},
+	})
+	if _, err = repo.CreateTag("v1", first, nil); err != nil {
+		t.Fatal(err)
+	}
+	commitFixtureTree(t, repo, workDir, []*fixture{
+		{Category: "app", Name: "bar", Version: "1.0", InTree: true},
+	})
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The two references of the same repository are resolved before
+	// they are loaded, for example the runtime and the build trees.
+	trees, err := ResolveTrees(context.Background(), []string{
+		"git+" + workDir + "#v1",
+		"git+" + workDir + "#" + head.Name().Short(),
+	}, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if trees[0].Path == trees[1].Path || trees[0].Revision != first.String() ||
+		trees[1].Revision != head.Hash().String() {
+		t.Fatalf("Unexpected trees %+v %+v", trees[0], trees[1])
+	}
+	assertTreePackages(t, trees[0], "app/foo-1.0")
+	assertTreePackages(t, trees[1], "app/foo-1.0", "app/bar-1.0")
+}
+
+func writeTreeTarball(t *testing.T, file string, files map[string]string) {
+	t.Helper()
+
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var tw *tar.Writer
+	if strings.HasSuffix(file, ".zst") {
+		zw, err := zstd.NewWriter(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer zw.Close()
+		tw = tar.NewWriter(zw)
+	} else {
+		gw := gzip.NewWriter(f)
+		defer gw.Close()
+		tw = tar.NewWriter(gw)
+	}
+	defer tw.Close()
+
+	for name, content := range files {
+		err = tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(content)),
+			Typeflag: tar.TypeReg,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestResolveTarballTree(t *testing.T) {
+	fixtures := []*fixture{
+		{Category: "app", Name: "foo", Version: "1.0", InTree: true},
+		{Category: "lib", Name: "bar", Version: "2.0", InTree: true},
+	}
+
+	files := make(map[string]string, 0)
+	for _, f := range fixtures {
+		data, err := f.Package().Yaml()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[filepath.Join("tree", f.Category, f.Name, f.Version,
+			anise_pkg.PackageDefinitionFile)] = string(data)
+	}
+
+	cacheDir := t.TempDir()
+	for _, name := range []string{"tree.tar.gz", "compilertree.tar.zst"} {
+		file := filepath.Join(t.TempDir(), name)
+		writeTreeTarball(t, file, files)
+
+		tree, err := ResolveTree(context.Background(), file, cacheDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tree.Kind != TreeKindTarball || !strings.HasPrefix(tree.Revision, "sha256:") {
+			t.Fatalf("Unexpected tree %+v", tree)
+		}
+		assertTreePackages(t, tree, "app/foo-1.0", "lib/bar-2.0")
+
+		// The tarball is extracted only once.
+		again, err := ResolveTree(context.Background(), file, cacheDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again.Path != tree.Path {
+			t.Errorf("Unexpected path %s, expected %s", again.Path, tree.Path)
+		}
+	}
+
+	file := filepath.Join(t.TempDir(), "tree.tar.gz")
+	writeTreeTarball(t, file, map[string]string{"../evil": "x"})
+	if _, err := ResolveTree(context.Background(), file, cacheDir); err == nil {
+		t.Error("Expected error with a path outside the tree")
+	}
+}
+
+func TestFetchRepositoryTrees(t *testing.T) {
+	s := specs.NewAniseRDConfig()
+	fixtures := []*fixture{
+		{Category: "app", Name: "foo", Version: "1.0", InTree: true, WithMeta: true, WithTarball: true},
+	}
+	knife, b := newFixtureKnife(t, s, fixtures)
+
+	if _, _, err := knife.FetchRepositoryTrees(context.Background(), t.TempDir()); err == nil {
+		t.Error("Expected error without published trees")
+	}
+
+	addTree := func(name string, fixtures []*fixture) {
+		files := make(map[string]string, 0)
+		for _, f := range fixtures {
+			data, err := f.Package().Yaml()
+			if err != nil {
+				t.Fatal(err)
+			}
+			files[filepath.Join(f.Category, f.Name, f.Version,
+				anise_pkg.PackageDefinitionFile)] = string(data)
+		}
+
+		file := filepath.Join(t.TempDir(), name)
+		writeTreeTarball(t, file, files)
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.AddFile(name, data)
+	}
+
+	addTree("compilertree.tar.gz", []*fixture{
+		{Category: "app", Name: "foo", Version: "1.0"},
+		{Category: "app", Name: "bar", Version: "1.0"},
+	})
+
+	runtime, build, err := knife.FetchRepositoryTrees(context.Background(), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime != build || runtime.Source != "repository:compilertree.tar.gz" {
+		t.Fatalf("Unexpected trees %+v %+v", runtime, build)
+	}
+
+	addTree("tree.tar.zst", []*fixture{{Category: "app", Name: "foo", Version: "1.0"}})
+	runtime, build, err = knife.FetchRepositoryTrees(context.Background(), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.Source != "repository:tree.tar.zst" ||
+		build.Source != "repository:compilertree.tar.gz" {
+		t.Fatalf("Unexpected trees %+v %+v", runtime, build)
+	}
+	assertTreePackages(t, runtime, "app/foo-1.0")
+	assertTreePackages(t, build, "app/bar-1.0", "app/foo-1.0")
+
+	// The published trees aren't removed by the cleaner.
+	if err = knife.LoadTreeSources([]*TreeSource{runtime}); err != nil {
+		t.Fatal(err)
+	}
+	s.Cleaner.FilesPolicy.Unknown = specs.FilePolicyDelete
+	if err = knife.Analyze(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	assertFiles(t, knife.Files2Remove)
+}
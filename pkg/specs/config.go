@@ -8,6 +8,9 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
 
 	"github.com/macaroni-os/anise-repo-devkit/pkg/version"
 
@@ -16,6 +19,21 @@ import (
 	"gopkg.in/yaml.v2"
 )
 
+const (
+	RequiredPolicyKeep   = "keep"
+	RequiredPolicyWarn   = "warn"
+	RequiredPolicyIgnore = "ignore"
+
+	DefaultMaxDeletePercent = 50.0
+
+	DuplicatesPolicyWarn   = "warn"
+	DuplicatesPolicyDelete = "delete"
+
+	FilePolicyKeep   = "keep"
+	FilePolicyWarn   = "warn"
+	FilePolicyDelete = "delete"
+)
+
 func NewAniseRDConfig() *AniseRDConfig {
 	return &AniseRDConfig{
 		Cleaner: AniseRDCCleaner{
@@ -26,11 +44,158 @@ func NewAniseRDConfig() *AniseRDConfig {
 
 func (c *AniseRDConfig) GetCleaner() *AniseRDCCleaner { return &c.Cleaner }
 func (c *AniseRDConfig) GetList() *AniseRDCList       { return &c.List }
+func (c *AniseRDConfig) GetCollisions() *AniseRDCCollisions {
+	return &c.Collisions
+}
+func (c *AniseRDConfig) GetLicenses() *AniseRDCLicenses { return &c.Licenses }
+
+// GetJournalFile returns the path of the journal. The default path
+// is $XDG_STATE_HOME/anise-repo-devkit/journal.jsonl.
+func (c *AniseRDConfig) GetJournalFile() string {
+	if c.JournalFile != "" {
+		return os.ExpandEnv(c.JournalFile)
+	}
+
+	stateDir := os.Getenv("XDG_STATE_HOME")
+	if stateDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			home = os.TempDir()
+		}
+		stateDir = filepath.Join(home, ".local", "state")
+	}
+
+	return filepath.Join(stateDir, "anise-repo-devkit", "journal.jsonl")
+}
+
+func (c *AniseRDConfig) GetRepository(name string) (*AniseRDRepository, error) {
+	for idx := range c.Repositories {
+		if c.Repositories[idx].Name == name {
+			return &c.Repositories[idx], nil
+		}
+	}
+	return nil, errors.New(fmt.Sprintf("Repository %s not found", name))
+}
+
+// ApplyRepository replaces the global cleaner and list sections
+// with the overrides defined by the repository.
+func (c *AniseRDConfig) ApplyRepository(r *AniseRDRepository) {
+	if r.Cleaner != nil {
+		c.Cleaner = *r.Cleaner
+	}
+	if r.List != nil {
+		c.List = *r.List
+	}
+	if r.Collisions != nil {
+		c.Collisions = *r.Collisions
+	}
+	if r.Licenses != nil {
+		c.Licenses = *r.Licenses
+	}
+}
+
+func (r *AniseRDRepository) GetName() string { return r.Name }
+
+// GetOptions returns the backend options with the environment
+// variables expanded.
+func (r *AniseRDRepository) GetOptions() map[string]string {
+	ans := make(map[string]string, 0)
+	for k, v := range r.Options {
+		ans[k] = os.ExpandEnv(v)
+	}
+	return ans
+}
 
 func (c *AniseRDCCleaner) HasExcludes() bool {
 	return len(c.Excludes) > 0
 }
 
+func (c *AniseRDCCleaner) GetRequiredPolicy() string {
+	if c.RequiredPolicy == "" {
+		return RequiredPolicyKeep
+	}
+	return c.RequiredPolicy
+}
+
+func (c *AniseRDCCleaner) GetMaxDeletePercent() float64 {
+	if c.MaxDeletePercent <= 0 {
+		return DefaultMaxDeletePercent
+	}
+	return c.MaxDeletePercent
+}
+
+func (c *AniseRDCCleaner) GetMaxDeleteFiles() int { return c.MaxDeleteFiles }
+
+func (c *AniseRDCCleaner) GetDuplicatesPolicy() string {
+	if c.DuplicatesPolicy == "" {
+		return DuplicatesPolicyWarn
+	}
+	return c.DuplicatesPolicy
+}
+
+// GetFilePolicy returns the policy of the class of files. The signature
+// and checksum files are kept and the unknown files are kept with
+// a warning by default.
+func (c *AniseRDCCleaner) GetFilePolicy(class string) string {
+	ans := ""
+	switch class {
+	case "signature":
+		ans = c.FilesPolicy.Signature
+	case "checksum":
+		ans = c.FilesPolicy.Checksum
+	case "unknown":
+		ans = c.FilesPolicy.Unknown
+		if ans == "" {
+			ans = FilePolicyWarn
+		}
+	}
+
+	if ans == "" {
+		ans = FilePolicyKeep
+	}
+	return ans
+}
+
+// IsAllowedPath returns true if the path could be shipped
+// by multiple packages.
+func (c *AniseRDCCollisions) IsAllowedPath(p string) bool {
+	for _, r := range c.AllowedPaths {
+		if match, _ := regexp.MatchString(r, p); match {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *AniseRDCCollisions) IsExcludedPkg(pkg *anise_pkg.DefaultPackage) bool {
+	return MatchPackages(c.ExcludePkgs, pkg)
+}
+
+// IsAllowedLicense returns true if the license is not denied and
+// it's in the allowed list when the list is defined.
+func (c *AniseRDCLicenses) IsAllowedLicense(l string) bool {
+	for _, d := range c.Denied {
+		if d == l {
+			return false
+		}
+	}
+
+	if len(c.Allowed) == 0 {
+		return true
+	}
+
+	for _, a := range c.Allowed {
+		if a == l {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *AniseRDCLicenses) IsExcludedPkg(pkg *anise_pkg.DefaultPackage) bool {
+	return MatchPackages(c.ExcludePkgs, pkg)
+}
+
 func (c *AniseRDCList) HasFilters() bool {
 	return len(c.ExcludePkgs) > 0
 }
@@ -43,9 +208,26 @@ func (p *AnisePackage) HumanReadableString() string {
 }
 
 func (c *AniseRDCList) ToIgnore(pkg *anise_pkg.DefaultPackage) bool {
+	return MatchPackages(c.ExcludePkgs, pkg)
+}
+
+func (c *AniseRDCCleaner) HasExcludePkgs() bool {
+	return len(c.ExcludePkgs) > 0
+}
+
+// IsExcludedPkg returns true if the package matches one of the
+// exclude_pkgs selectors of the cleaner.
+func (c *AniseRDCCleaner) IsExcludedPkg(pkg *anise_pkg.DefaultPackage) bool {
+	return MatchPackages(c.ExcludePkgs, pkg)
+}
+
+// MatchPackages returns true if the package matches one of the selectors.
+// A selector without version matches all versions of the package.
+// The package is considered matched if its version is not valid.
+func MatchPackages(selectors []AnisePackage, pkg *anise_pkg.DefaultPackage) bool {
 	ans := false
 
-	if c.HasFilters() {
+	if len(selectors) > 0 {
 
 		pSelector, err := version.ParseVersion(pkg.GetVersion())
 		if err != nil {
@@ -55,12 +237,16 @@ func (c *AniseRDCList) ToIgnore(pkg *anise_pkg.DefaultPackage) bool {
 			return true
 		}
 
-		for _, f := range c.ExcludePkgs {
+		for _, f := range selectors {
 			if f.GetName() != pkg.GetName() ||
 				f.GetCategory() != pkg.GetCategory() {
 				continue
 			}
 
+			if f.GetVersion() == "" {
+				return true
+			}
+
 			selector, err := version.ParseVersion(f.GetVersion())
 			if err != nil {
 				Warning(fmt.Sprintf(
@@ -91,9 +277,120 @@ func SpecsFromYaml(data []byte) (*AniseRDConfig, error) {
 	if err := yaml.Unmarshal(data, ans); err != nil {
 		return nil, err
 	}
+
+	if err := ans.Validate(); err != nil {
+		return nil, err
+	}
+
 	return ans, nil
 }
 
+func (c *AniseRDConfig) Validate() error {
+	cleaners := []*AniseRDCCleaner{&c.Cleaner}
+	for idx := range c.Repositories {
+		if c.Repositories[idx].Name == "" {
+			return errors.New("Found repository without name")
+		}
+		if c.Repositories[idx].Cleaner != nil {
+			cleaners = append(cleaners, c.Repositories[idx].Cleaner)
+		}
+	}
+
+	collisions := []*AniseRDCCollisions{&c.Collisions}
+	for idx := range c.Repositories {
+		if c.Repositories[idx].Collisions != nil {
+			collisions = append(collisions, c.Repositories[idx].Collisions)
+		}
+	}
+	for _, col := range collisions {
+		for _, r := range col.AllowedPaths {
+			if _, err := regexp.Compile(r); err != nil {
+				return errors.New(fmt.Sprintf("Invalid collisions allowed path %s: %s",
+					r, err.Error()))
+			}
+		}
+	}
+
+	licenses := []*AniseRDCLicenses{&c.Licenses}
+	for idx := range c.Repositories {
+		if c.Repositories[idx].Licenses != nil {
+			licenses = append(licenses, c.Repositories[idx].Licenses)
+		}
+	}
+	for _, l := range licenses {
+		for _, a := range l.Allowed {
+			for _, d := range l.Denied {
+				if a == d {
+					return errors.New(fmt.Sprintf(
+						"Invalid license %s: it's both allowed and denied", a))
+				}
+			}
+		}
+	}
+
+	for _, cleaner := range cleaners {
+		if cleaner.MaxDeletePercent < 0 || cleaner.MaxDeletePercent > 100 {
+			return errors.New(fmt.Sprintf("Invalid max_delete_percent %f",
+				cleaner.MaxDeletePercent))
+		}
+		if cleaner.MaxDeleteFiles < 0 {
+			return errors.New(fmt.Sprintf("Invalid max_delete_files %d",
+				cleaner.MaxDeleteFiles))
+		}
+
+		for _, p := range []string{
+			cleaner.FilesPolicy.Signature,
+			cleaner.FilesPolicy.Checksum,
+			cleaner.FilesPolicy.Unknown,
+		} {
+			switch p {
+			case "", FilePolicyKeep, FilePolicyWarn, FilePolicyDelete:
+			default:
+				return errors.New(fmt.Sprintf("Invalid files policy %s", p))
+			}
+		}
+
+		for _, p := range cleaner.ExcludePkgs {
+			if p.GetName() == "" || p.GetCategory() == "" {
+				return errors.New(fmt.Sprintf(
+					"Invalid exclude_pkgs entry %s: name and category are mandatory",
+					p.HumanReadableString()))
+			}
+			if p.GetVersion() != "" {
+				if _, err := version.ParseVersion(p.GetVersion()); err != nil {
+					return errors.New(fmt.Sprintf(
+						"Invalid version of exclude_pkgs entry %s: %s",
+						p.HumanReadableString(), err.Error()))
+				}
+			}
+		}
+
+		switch cleaner.GetDuplicatesPolicy() {
+		case DuplicatesPolicyWarn, DuplicatesPolicyDelete:
+		default:
+			return errors.New(fmt.Sprintf("Invalid duplicates_policy %s",
+				cleaner.DuplicatesPolicy))
+		}
+
+		for _, c := range cleaner.CompressionPreference {
+			switch c {
+			case "zstd", "gzip", "none":
+			default:
+				return errors.New(fmt.Sprintf("Invalid compression_preference %s", c))
+			}
+		}
+
+		switch cleaner.GetRequiredPolicy() {
+		case RequiredPolicyKeep, RequiredPolicyWarn, RequiredPolicyIgnore:
+		default:
+			return errors.New(fmt.Sprintf("Invalid required_policy %s",
+				cleaner.RequiredPolicy))
+		}
+	}
+
+	return nil
+}
+
 func LoadSpecsFile(file string) (*AniseRDConfig, error) {
 	if file == "" {
 		return nil, errors.New("Invalid file path")
@@ -0,0 +1,143 @@
+/*
+Copyright © 2020-2025 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package specs
+
+import (
+	"testing"
+)
+
+const repositoriesSpecs = `
+cleaner:
+  excludes:
+    - global.tar
+  duplicates_policy: warn
+list:
+  exclude_pkgs:
+    - category: global
+      name: pkg
+repositories:
+  - name: main
+    backend: minio
+    options:
+      minio-bucket: ${TEST_BUCKET}-main
+      minio-endpoint: $TEST_ENDPOINT
+      minio-region: eu-west-1
+    cleaner:
+      excludes:
+        - main.tar
+      duplicates_policy: delete
+  - name: testing
+    backend: local
+    path: /srv/testing
+`
+
+func TestGetRepository(t *testing.T) {
+	config, err := SpecsFromYaml([]byte(repositoriesSpecs))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		backend string
+		path    string
+		err     bool
+	}{
+		{"main", "minio", "", false},
+		{"testing", "local", "/srv/testing", false},
+		{"missing", "", "", true},
+		{"", "", "", true},
+	}
+
+	for _, tt := range tests {
+		repo, err := config.GetRepository(tt.name)
+		if tt.err {
+			if err == nil {
+				t.Fatalf("%s: expected an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error %s", tt.name, err.Error())
+		}
+		if repo.GetName() != tt.name || repo.Backend != tt.backend || repo.Path != tt.path {
+			t.Fatalf("%s: unexpected repository %+v", tt.name, repo)
+		}
+	}
+}
+
+func TestApplyRepository(t *testing.T) {
+	tests := []struct {
+		name       string
+		excludes   []string
+		duplicates string
+		listPkgs   int
+	}{
+		// The cleaner is replaced and the list is the global one.
+		{"main", []string{"main.tar"}, DuplicatesPolicyDelete, 1},
+		// Without overrides the global sections are kept.
+		{"testing", []string{"global.tar"}, DuplicatesPolicyWarn, 1},
+	}
+
+	for _, tt := range tests {
+		config, err := SpecsFromYaml([]byte(repositoriesSpecs))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		repo, err := config.GetRepository(tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config.ApplyRepository(repo)
+
+		cleaner := config.GetCleaner()
+		if len(cleaner.Excludes) != len(tt.excludes) || cleaner.Excludes[0] != tt.excludes[0] {
+			t.Fatalf("%s: unexpected excludes %v", tt.name, cleaner.Excludes)
+		}
+		if cleaner.GetDuplicatesPolicy() != tt.duplicates {
+			t.Fatalf("%s: unexpected duplicates policy %s", tt.name,
+				cleaner.GetDuplicatesPolicy())
+		}
+		if len(config.GetList().ExcludePkgs) != tt.listPkgs {
+			t.Fatalf("%s: unexpected list excludes %v", tt.name, config.GetList().ExcludePkgs)
+		}
+	}
+}
+
+func TestRepositoryGetOptions(t *testing.T) {
+	t.Setenv("TEST_BUCKET", "packages")
+	t.Setenv("TEST_ENDPOINT", "s3.example.org")
+
+	config, err := SpecsFromYaml([]byte(repositoriesSpecs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := config.GetRepository("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		option   string
+		expected string
+	}{
+		{"minio-bucket", "packages-main"},
+		{"minio-endpoint", "s3.example.org"},
+		{"minio-region", "eu-west-1"},
+	}
+
+	opts := repo.GetOptions()
+	for _, tt := range tests {
+		if opts[tt.option] != tt.expected {
+			t.Fatalf("Expected %s for %s, got %s", tt.expected, tt.option, opts[tt.option])
+		}
+	}
+
+	// The options of the repository are not changed.
+	if repo.Options["minio-bucket"] != "${TEST_BUCKET}-main" {
+		t.Fatalf("Unexpected change of the repository options %v", repo.Options)
+	}
+}
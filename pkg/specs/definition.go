@@ -5,22 +5,107 @@ See AUTHORS and LICENSE for the license details and contributors.
 package specs
 
 import (
+	"context"
+	"time"
+
 	artifact "github.com/geaaru/luet/pkg/v2/compiler/types/artifact"
 )
 
 type AniseRDConfig struct {
-	Cleaner AniseRDCCleaner `json:"cleaner,omitempty" yaml:"cleaner,omitempty"`
-	List    AniseRDCList    `json:"list,omitempty" yaml:"list,omitempty"`
+	Cleaner      AniseRDCCleaner     `json:"cleaner,omitempty" yaml:"cleaner,omitempty"`
+	List         AniseRDCList        `json:"list,omitempty" yaml:"list,omitempty"`
+	Collisions   AniseRDCCollisions  `json:"collisions,omitempty" yaml:"collisions,omitempty"`
+	Licenses     AniseRDCLicenses    `json:"licenses,omitempty" yaml:"licenses,omitempty"`
+	Repositories []AniseRDRepository `json:"repositories,omitempty" yaml:"repositories,omitempty"`
+
+	// Path of the journal where the write operations are recorded.
+	JournalFile string `json:"journal_file,omitempty" yaml:"journal_file,omitempty"`
 }
 
 type AniseRDCCleaner struct {
 	Excludes []string `json:"excludes,omitempty" yaml:"excludes,omitempty"`
+	// Packages to never remove. The metadata and the tarball of the
+	// matching artifacts are kept whatever are their file names.
+	ExcludePkgs []AnisePackage `json:"exclude_pkgs,omitempty" yaml:"exclude_pkgs,omitempty"`
+
+	// Define how to manage the artifacts to remove that are still required
+	// by the artifacts that remain in the repository: keep|warn|ignore.
+	// Default is keep.
+	RequiredPolicy string `json:"required_policy,omitempty" yaml:"required_policy,omitempty"`
+
+	// Safety guards: the cleaner aborts when the files to remove are
+	// more than the percentage of the repository files or more than
+	// the max number of files. Default is 50%. 0 to disable the max files.
+	MaxDeletePercent float64 `json:"max_delete_percent,omitempty" yaml:"max_delete_percent,omitempty"`
+	MaxDeleteFiles   int     `json:"max_delete_files,omitempty" yaml:"max_delete_files,omitempty"`
+
+	// Policy of the files that aren't index, metadata or package files.
+	FilesPolicy AniseRDCFilesPolicy `json:"files_policy,omitempty" yaml:"files_policy,omitempty"`
+
+	// Define how to manage the artifacts of the same package
+	// (category/name/version): warn|delete. Default is warn.
+	DuplicatesPolicy string `json:"duplicates_policy,omitempty" yaml:"duplicates_policy,omitempty"`
+	// Compressions ordered by preference used to select the artifact
+	// to keep between the duplicates: zstd|gzip|none. The artifact with
+	// the newest build timestamp is kept between artifacts with the
+	// same preference.
+	CompressionPreference []string `json:"compression_preference,omitempty" yaml:"compression_preference,omitempty"`
+}
+
+// AniseRDCFilesPolicy defines for every class of files what the cleaner
+// does: keep|warn|delete. Warn keeps the file with a warning.
+// The signature and checksum policies are applied only to the sidecars
+// of files that are missing or marked for removal.
+type AniseRDCFilesPolicy struct {
+	Signature string `json:"signature,omitempty" yaml:"signature,omitempty"`
+	Checksum  string `json:"checksum,omitempty" yaml:"checksum,omitempty"`
+	Unknown   string `json:"unknown,omitempty" yaml:"unknown,omitempty"`
+}
+
+// AniseRDCCollisions defines the files shipped by multiple
+// packages that are not reported as collisions.
+type AniseRDCCollisions struct {
+	// Regex of the paths that could be shipped by multiple packages.
+	AllowedPaths []string `json:"allowed_paths,omitempty" yaml:"allowed_paths,omitempty"`
+	// Packages to ignore on check collisions.
+	ExcludePkgs []AnisePackage `json:"exclude_pkgs,omitempty" yaml:"exclude_pkgs,omitempty"`
+}
+
+// AniseRDCLicenses defines the licenses admitted in the repository.
+type AniseRDCLicenses struct {
+	// Licenses admitted. If empty all licenses not denied are admitted.
+	Allowed []string `json:"allowed,omitempty" yaml:"allowed,omitempty"`
+	// Licenses not admitted.
+	Denied []string `json:"denied,omitempty" yaml:"denied,omitempty"`
+	// Report the packages without license as violations.
+	RequireLicense bool `json:"require_license,omitempty" yaml:"require_license,omitempty"`
+	// Packages to ignore on check licenses.
+	ExcludePkgs []AnisePackage `json:"exclude_pkgs,omitempty" yaml:"exclude_pkgs,omitempty"`
 }
 
 type AniseRDCList struct {
 	ExcludePkgs []AnisePackage `json:"exclude_pkgs,omitempty" yaml:"exclude_pkgs,omitempty"`
 }
 
+type AniseRDRepository struct {
+	Name    string `json:"name" yaml:"name"`
+	Backend string `json:"backend,omitempty" yaml:"backend,omitempty"`
+	Path    string `json:"path,omitempty" yaml:"path,omitempty"`
+	// Backend options. The values support environment variables
+	// interpolation with the $VAR or ${VAR} syntax.
+	Options   map[string]string `json:"options,omitempty" yaml:"options,omitempty"`
+	TreePaths []string          `json:"tree_paths,omitempty" yaml:"tree_paths,omitempty"`
+	// Trees with the compiler definitions used to sort the packages to
+	// build. Default are the tree_paths.
+	BuildTreePaths []string `json:"build_tree_paths,omitempty" yaml:"build_tree_paths,omitempty"`
+
+	// Optional overrides of the global sections.
+	Cleaner    *AniseRDCCleaner    `json:"cleaner,omitempty" yaml:"cleaner,omitempty"`
+	List       *AniseRDCList       `json:"list,omitempty" yaml:"list,omitempty"`
+	Collisions *AniseRDCCollisions `json:"collisions,omitempty" yaml:"collisions,omitempty"`
+	Licenses   *AniseRDCLicenses   `json:"licenses,omitempty" yaml:"licenses,omitempty"`
+}
+
 type AnisePackage struct {
 	Name     string `json:"name" yaml:"name"`
 	Category string `json:"category" yaml:"category"`
@@ -28,7 +113,61 @@ type AnisePackage struct {
 }
 
 type RepoBackendHandler interface {
-	GetFilesList() ([]string, error)
-	GetMetadata(string) (*artifact.PackageArtifact, error)
-	CleanFile(string) error
+	GetFilesList(context.Context) ([]string, error)
+	GetMetadata(context.Context, string) (*artifact.PackageArtifact, error)
+	CleanFile(context.Context, string) error
+}
+
+// RepoFile describes a file of the repository.
+type RepoFile struct {
+	Name         string
+	Size         int64
+	LastModified time.Time
+}
+
+// RepoBackendFilesInfo is implemented by the backends that are able
+// to return the size of the files with the list of the files.
+type RepoBackendFilesInfo interface {
+	GetFilesInfo(context.Context) ([]RepoFile, error)
+}
+
+// RepoBackendDownloader is implemented by the backends that are able
+// to download a file of the repository in a local file.
+type RepoBackendDownloader interface {
+	DownloadFile(ctx context.Context, file, dst string) error
+}
+
+// CleanResult contains the result of the removal of a file.
+type CleanResult struct {
+	File  string
+	Error error
+	// Number of object versions removed. Used by versioned backends.
+	Versions int
+	// Storage reclaimed in bytes if known by the backend.
+	Reclaimed int64
+}
+
+// RepoBackendBatchCleaner is implemented by the backends that are able
+// to remove multiple files with less requests than CleanFile.
+type RepoBackendBatchCleaner interface {
+	CleanFiles(context.Context, []string) []CleanResult
+}
+
+// ObjectVersion describes a version of an object of a versioned backend.
+type ObjectVersion struct {
+	File           string
+	VersionID      string
+	Size           int64
+	LastModified   time.Time
+	IsDeleteMarker bool
+}
+
+// RepoBackendVersioned is implemented by the backends that support
+// the versioning of the objects.
+type RepoBackendVersioned interface {
+	IsVersioned() bool
+	// Returns the noncurrent versions that are noncurrent before
+	// the specified time.
+	GetNoncurrentVersions(context.Context, time.Time) ([]ObjectVersion, error)
+	PurgeVersions(context.Context, []ObjectVersion) []CleanResult
 }